@@ -40,9 +40,12 @@ func (e *DownloadError) Error() string {
 }
 
 type Downloader struct {
-	Url        string
-	SaveDir    string
-	Override   bool
+	Url      string
+	SaveDir  string
+	Override bool
+	// Policy 决定下载文件的命名方式，为nil时根据Override选择
+	// Overwrite或者RenameWithCounter
+	Policy     NamingPolicy
 	onFinish   func(filepath string)
 	onCancel   func()
 	onError    func(error)
@@ -99,38 +102,9 @@ func (d *Downloader) genFilename() (filename string) {
 		filename = util.RandStrN(32)
 	}
 
-	var basename, suffix string
-	index := strings.LastIndex(filename, ".")
-	if index < 0 {
-		// dot not exists
-		basename = filename
-	} else if index == 0 {
+	if strings.Index(filename, ".") == 0 {
 		// remove the dot
-		basename = string([]rune(filename)[1:])
-		filename = basename
-	} else if index > 0 {
-		basename = string([]rune(filename)[:index])
-		// suffix contains the dot(.)
-		suffix = string([]rune(filename)[index:])
-	}
-
-	num := 1
-	for {
-		fullpath := filepath.Join(d.SaveDir, filename)
-		if _, err := os.Stat(fullpath); err == nil {
-			// file exists
-			if d.Override {
-				// remove exists
-				os.Remove(fullpath)
-				break
-			} else {
-				// find another name by append suffix
-				filename = fmt.Sprintf("%s(%d)%s", basename, num, suffix)
-				num++
-			}
-		} else {
-			break
-		}
+		filename = string([]rune(filename)[1:])
 	}
 	return
 }
@@ -143,6 +117,7 @@ func (d *Downloader) genFilename() (filename string) {
 // 这三个参数会覆盖Downloader对应的三个方法。
 // 分别是OnFinish(f func())以及OnError(f func(int, error))
 // 以及OnProgress(f func(int64, int64))。
+// 保存的文件名由Policy决定，参见NamingPolicy。
 func (d *Downloader) Start(callbacks ...interface{}) {
 	switch len(callbacks) {
 	case 1:
@@ -157,23 +132,31 @@ func (d *Downloader) Start(callbacks ...interface{}) {
 	}
 
 	fn := d.genFilename()
-	fullpath := filepath.Join(d.SaveDir, fn)
+	policy := d.policy()
+
+	file, err := policy.Create(d.SaveDir, fn)
+	if err == ErrFileExists {
+		fullpath := filepath.Join(d.SaveDir, fn)
+		log.Info("file [%v] exists, skip download [%v]", fullpath, d.Url)
+		call(d.onFinish, fullpath)
+		return
+	} else if err != nil {
+		err = &DownloadError{CREATE_FILE_FAILED, err.Error()}
+	} else if err = d.download(file); err != nil {
+		file.Close()
+		if re := os.Remove(file.Name()); re != nil {
+			log.Error("remove file failed: %v", re)
+		}
+	}
 
-	if _, err := os.Stat(fullpath); err == nil && d.Override {
-		if d.Override {
-			// remove exists
-			os.Remove(fullpath)
-		} else {
-			fullpath = filepath.Join()
+	var fullpath string
+	if err == nil {
+		if fullpath, err = policy.Commit(file, d.SaveDir, fn); err != nil {
+			err = &DownloadError{SAVE_FAILED, err.Error()}
 		}
 	}
 
-	err := d.download(fullpath)
 	if err != nil {
-		re := os.Remove(fullpath)
-		if re != nil {
-			log.Error("remove file failed: %v", re)
-		}
 		msg := fmt.Errorf("download [%v] failed. error is: %v", d.Url, err)
 		log.Error(msg)
 		call(d.onError, msg)
@@ -183,15 +166,18 @@ func (d *Downloader) Start(callbacks ...interface{}) {
 	}
 }
 
-func (d *Downloader) download(localpath string) error {
-	file, err := os.Create(localpath)
-	defer file.Close()
-
-	if err != nil {
-		e := &DownloadError{CREATE_FILE_FAILED, err.Error()}
-		return e
+func (d *Downloader) policy() NamingPolicy {
+	if d.Policy != nil {
+		return d.Policy
 	}
+	if d.Override {
+		return Overwrite
+	}
+	return RenameWithCounter
+}
 
+func (d *Downloader) download(file *os.File) error {
+	localpath := file.Name()
 	req, err := http.NewRequest("GET", d.Url, nil)
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -0,0 +1,185 @@
+// Description: naming.go 提供下载文件的命名策略。
+// 所有策略都通过O_EXCL方式独占创建文件，多个下载器同时写入同一个目录时不会互相覆盖。
+// Since: 2026-10-19
+package download
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrFileExists 由SkipIfExists策略返回，表示目标文件已存在，无需再次下载。
+var ErrFileExists = errors.New("file already exists")
+
+// NamingPolicy 决定下载内容最终保存到哪个文件。
+//
+// Create在下载开始前调用，返回用于写入下载内容的文件，该文件必须是独占创建的。
+// Commit在下载成功后调用，返回最终保存的文件路径。
+// 下载失败时，Create返回的文件会被直接删除。
+type NamingPolicy interface {
+	Create(dir, filename string) (*os.File, error)
+	Commit(file *os.File, dir, filename string) (string, error)
+}
+
+var (
+	// Overwrite 先下载到临时文件，成功后再原子地替换已有文件。
+	// 下载失败时已有文件保持不变。
+	Overwrite NamingPolicy = overwritePolicy{}
+
+	// SkipIfExists 在目标文件已存在时跳过下载。
+	SkipIfExists NamingPolicy = skipPolicy{}
+
+	// RenameWithCounter 在目标文件已存在时依次尝试name(1).ext、name(2).ext...
+	RenameWithCounter NamingPolicy = counterPolicy{}
+
+	// RenameWithTimestamp 在目标文件已存在时使用name_20060102150405.ext，
+	// 同一秒内仍然冲突则再追加计数。
+	RenameWithTimestamp NamingPolicy = timestampPolicy{}
+
+	// ContentDigest 使用下载内容的SHA-256摘要作为文件名，
+	// 内容相同的文件只会保存一份。
+	ContentDigest NamingPolicy = digestPolicy{}
+)
+
+// createExcl 以O_EXCL方式创建文件，文件已存在时返回的错误满足os.IsExist。
+func createExcl(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+}
+
+// createTemp 在dir中为filename创建一个隐藏的临时文件。
+// 临时文件与目标文件位于同一目录，保证之后的Rename/Link是原子的。
+// 与createExcl一样以0666(受umask影响)创建，而不是ioutil.TempFile的0600，
+// 保证保存的文件与其他策略创建的文件权限一致。
+func createTemp(dir, filename string) (*os.File, error) {
+	for {
+		name := "." + filename + "." + strconv.FormatInt(rand.Int63(), 36)
+		f, err := createExcl(filepath.Join(dir, name))
+		if err == nil || !os.IsExist(err) {
+			return f, err
+		}
+	}
+}
+
+// splitExt 将文件名拆分为主文件名和后缀，后缀包含点号(.)
+func splitExt(filename string) (basename, suffix string) {
+	index := strings.LastIndex(filename, ".")
+	if index <= 0 {
+		return filename, ""
+	}
+	return filename[:index], filename[index:]
+}
+
+// createWithCounter 依次尝试first以及basename(n)suffix，直到独占创建成功。
+func createWithCounter(dir, first, basename, suffix string) (*os.File, error) {
+	name := first
+	for num := 1; ; num++ {
+		f, err := createExcl(filepath.Join(dir, name))
+		if err == nil || !os.IsExist(err) {
+			return f, err
+		}
+		name = fmt.Sprintf("%s(%d)%s", basename, num, suffix)
+	}
+}
+
+type overwritePolicy struct{}
+
+func (overwritePolicy) Create(dir, filename string) (*os.File, error) {
+	return createTemp(dir, filename)
+}
+
+func (overwritePolicy) Commit(file *os.File, dir, filename string) (string, error) {
+	fullpath := filepath.Join(dir, filename)
+	// 保留被替换文件的权限
+	if fi, err := os.Stat(fullpath); err == nil {
+		if err = file.Chmod(fi.Mode().Perm()); err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return "", err
+		}
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(file.Name(), fullpath); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return fullpath, nil
+}
+
+type skipPolicy struct{}
+
+func (skipPolicy) Create(dir, filename string) (*os.File, error) {
+	f, err := createExcl(filepath.Join(dir, filename))
+	if os.IsExist(err) {
+		return nil, ErrFileExists
+	}
+	return f, err
+}
+
+func (skipPolicy) Commit(file *os.File, dir, filename string) (string, error) {
+	return file.Name(), file.Close()
+}
+
+type counterPolicy struct{}
+
+func (counterPolicy) Create(dir, filename string) (*os.File, error) {
+	basename, suffix := splitExt(filename)
+	return createWithCounter(dir, filename, basename, suffix)
+}
+
+func (counterPolicy) Commit(file *os.File, dir, filename string) (string, error) {
+	return file.Name(), file.Close()
+}
+
+type timestampPolicy struct{}
+
+func (timestampPolicy) Create(dir, filename string) (*os.File, error) {
+	f, err := createExcl(filepath.Join(dir, filename))
+	if err == nil || !os.IsExist(err) {
+		return f, err
+	}
+	basename, suffix := splitExt(filename)
+	basename = basename + "_" + time.Now().Format("20060102150405")
+	return createWithCounter(dir, basename+suffix, basename, suffix)
+}
+
+func (timestampPolicy) Commit(file *os.File, dir, filename string) (string, error) {
+	return file.Name(), file.Close()
+}
+
+type digestPolicy struct{}
+
+func (digestPolicy) Create(dir, filename string) (*os.File, error) {
+	return createTemp(dir, filename)
+}
+
+func (digestPolicy) Commit(file *os.File, dir, filename string) (string, error) {
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	_, suffix := splitExt(filename)
+	fullpath := filepath.Join(dir, hex.EncodeToString(hash.Sum(nil))+suffix)
+
+	// Link不会覆盖已有文件，文件已存在说明相同内容之前已经下载过
+	if err := os.Link(file.Name(), fullpath); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+	return fullpath, nil
+}
@@ -0,0 +1,195 @@
+// Description: download
+// Since: 2026-10-19
+package download
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "download")
+	if err != nil {
+		t.Fatalf("create temp dir failed: %v", err)
+	}
+	return dir
+}
+
+func save(t *testing.T, policy NamingPolicy, dir, filename, content string) string {
+	f, err := policy.Create(dir, filename)
+	if err != nil {
+		t.Fatalf("create [%v] failed: %v", filename, err)
+	}
+	if _, err = f.WriteString(content); err != nil {
+		t.Fatalf("write [%v] failed: %v", f.Name(), err)
+	}
+	fullpath, err := policy.Commit(f, dir, filename)
+	if err != nil {
+		t.Fatalf("commit [%v] failed: %v", f.Name(), err)
+	}
+	return fullpath
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read [%v] failed: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestRenameWithCounterConcurrent(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	const n = 20
+	paths := make(chan string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			paths <- save(t, RenameWithCounter, dir, "file.txt", "data")
+		}()
+	}
+	wg.Wait()
+	close(paths)
+
+	seen := make(map[string]bool)
+	for p := range paths {
+		if seen[p] {
+			t.Errorf("path [%v] used twice", p)
+		}
+		seen[p] = true
+	}
+	if !seen[filepath.Join(dir, "file.txt")] || !seen[filepath.Join(dir, "file(1).txt")] {
+		t.Errorf("expect file.txt and file(1).txt, got %v", seen)
+	}
+}
+
+func TestOverwriteKeepsFileOnFailure(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	fullpath := save(t, Overwrite, dir, "file.txt", "old")
+
+	// a failed download only removes the file returned by Create
+	f, err := Overwrite.Create(dir, "file.txt")
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	if got := readFile(t, fullpath); got != "old" {
+		t.Errorf("expect old content, got %v", got)
+	}
+
+	if p := save(t, Overwrite, dir, "file.txt", "new"); p != fullpath {
+		t.Errorf("expect %v, got %v", fullpath, p)
+	}
+	if got := readFile(t, fullpath); got != "new" {
+		t.Errorf("expect new content, got %v", got)
+	}
+}
+
+func TestOverwriteFailedDownload(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	fullpath := save(t, Overwrite, dir, "file.txt", "old")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "failed", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	d, err := NewDownloader(server.URL+"/file.txt", dir, true)
+	if err != nil {
+		t.Fatalf("create downloader failed: %v", err)
+	}
+	var failed error
+	d.Start(func(string) {}, func(err error) { failed = err })
+	if failed == nil {
+		t.Errorf("expect download error")
+	}
+	if got := readFile(t, fullpath); got != "old" {
+		t.Errorf("expect old content, got %v", got)
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("expect the temp file to be removed, got %d files", len(files))
+	}
+}
+
+func TestOverwriteFileMode(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// 新文件与其他策略创建的文件权限一致
+	skipped, _ := os.Stat(save(t, SkipIfExists, dir, "skip.txt", "data"))
+	fullpath := save(t, Overwrite, dir, "file.txt", "data")
+	if fi, _ := os.Stat(fullpath); fi.Mode() != skipped.Mode() {
+		t.Errorf("expect mode %v, got %v", skipped.Mode(), fi.Mode())
+	}
+
+	// 替换文件时保留原有权限
+	if err := os.Chmod(fullpath, 0640); err != nil {
+		t.Fatalf("chmod failed: %v", err)
+	}
+	save(t, Overwrite, dir, "file.txt", "new")
+	if fi, _ := os.Stat(fullpath); fi.Mode().Perm() != 0640 {
+		t.Errorf("expect mode 0640, got %v", fi.Mode())
+	}
+}
+
+func TestSkipIfExists(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	save(t, SkipIfExists, dir, "file.txt", "data")
+	if _, err := SkipIfExists.Create(dir, "file.txt"); err != ErrFileExists {
+		t.Errorf("expect ErrFileExists, got %v", err)
+	}
+}
+
+func TestRenameWithTimestamp(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	first := save(t, RenameWithTimestamp, dir, "file.txt", "1")
+	second := save(t, RenameWithTimestamp, dir, "file.txt", "2")
+	third := save(t, RenameWithTimestamp, dir, "file.txt", "3")
+	if first != filepath.Join(dir, "file.txt") {
+		t.Errorf("expect file.txt, got %v", first)
+	}
+	if !strings.HasPrefix(filepath.Base(second), "file_") || filepath.Ext(second) != ".txt" {
+		t.Errorf("expect timestamped name, got %v", second)
+	}
+	if second == third {
+		t.Errorf("expect different names, got %v twice", second)
+	}
+}
+
+func TestContentDigest(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	a := save(t, ContentDigest, dir, "a.txt", "same")
+	b := save(t, ContentDigest, dir, "b.txt", "same")
+	c := save(t, ContentDigest, dir, "c.txt", "other")
+	if a != b {
+		t.Errorf("expect same path for same content, got %v and %v", a, b)
+	}
+	if a == c {
+		t.Errorf("expect different path for different content, got %v", a)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 2 {
+		t.Errorf("expect 2 files left, got %d", len(files))
+	}
+}
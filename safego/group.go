@@ -0,0 +1,69 @@
+// Description: safego/group.go runs functions in goroutines and waits for them, recovering panics as errors
+// Since: 2026-10-19
+package safego
+
+import (
+	"context"
+	"sync"
+)
+
+// Group runs functions in goroutines and waits for them, like errgroup.
+// A panic is recovered and turned into a *PanicError.
+//
+// The zero Group is ready to use and does not cancel anything.
+type Group struct {
+	cancel func()
+
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+// WithContext returns a Group and a context derived from ctx, which is
+// canceled when a function of the group fails or panics, or when Wait
+// returns.
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{cancel: cancel}, ctx
+}
+
+// Go runs f in a goroutine. The first error or panic is returned by Wait.
+func (g *Group) Go(f func() error) {
+	g.GoLabel("", f)
+}
+
+// GoLabel is Go, label names f in the *PanicError if it panics.
+func (g *Group) GoLabel(label string, f func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		var err error
+		defer func() {
+			if r := recover(); r != nil {
+				err = NewPanicError(r, label)
+			}
+			if err != nil {
+				g.fail(err)
+			}
+		}()
+		err = f()
+	}()
+}
+
+// Wait waits for all functions started by Go and returns the first error.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel()
+	}
+	return g.err
+}
+
+func (g *Group) fail(err error) {
+	g.errOnce.Do(func() {
+		g.err = err
+		if g.cancel != nil {
+			g.cancel()
+		}
+	})
+}
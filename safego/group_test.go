@@ -0,0 +1,67 @@
+// Description: safego
+// Since: 2026-10-19
+package safego
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	var g Group
+	results := make([]int, 10)
+	for i := range results {
+		i := i
+		g.Go(func() error {
+			results[i] = i * i
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatalf("Error waiting: %v", err)
+	}
+	if results[9] != 81 {
+		t.Errorf("Expected 81; Got %d", results[9])
+	}
+}
+
+func TestGroupPanic(t *testing.T) {
+	g, ctx := WithContext(context.Background())
+	g.Go(func() error {
+		a := []int{0}
+		_ = a[len(a)]
+		return nil
+	})
+	g.Go(func() error {
+		// Canceled by the panic.
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	err := g.Wait()
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("Expected a PanicError; Got %v", err)
+	}
+	if _, ok := panicErr.Value.(error); !ok {
+		t.Errorf("Expected a runtime error; Got %v", panicErr.Value)
+	}
+	if !bytes.Contains(panicErr.Stack, []byte("TestGroupPanic")) {
+		t.Errorf("Expected the stack of the panic; Got %s", panicErr.Stack)
+	}
+}
+
+func TestGroupError(t *testing.T) {
+	g, ctx := WithContext(context.Background())
+	failed := errors.New("failed")
+	g.Go(func() error {
+		return failed
+	})
+	if err := g.Wait(); err != failed {
+		t.Errorf("Expected %v; Got %v", failed, err)
+	}
+	if ctx.Err() == nil {
+		t.Errorf("Expected the context to be canceled")
+	}
+}
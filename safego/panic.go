@@ -0,0 +1,74 @@
+// Description: safego/panic.go describes recovered panics with the stack of the goroutine
+// Since: 2026-10-19
+package safego
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/kimiazhu/golib/stack"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PanicError describes a recovered panic.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+	// Label names the task which panicked, if it was given one.
+	Label     string
+	Goroutine int64
+	Time      time.Time
+	// Frames is the call stack, starting where the panic happened.
+	Frames []stack.Frame
+	// Stack is Frames printed like stack.CallStack.
+	Stack []byte
+}
+
+// NewPanicError returns the PanicError of value, which was just
+// recovered. Call it from the deferred function or from a Handler, so
+// that the stack of the panic is still there.
+func NewPanicError(value interface{}, label string) *PanicError {
+	frames := stack.Frames(1)
+	for i, f := range frames {
+		if f.Function == "gopanic" && strings.HasSuffix(f.File, "runtime/panic.go") {
+			frames = frames[i+1:]
+			break
+		}
+	}
+	return &PanicError{
+		Value:     value,
+		Label:     label,
+		Goroutine: goroutineID(),
+		Time:      time.Now(),
+		Frames:    frames,
+		Stack:     stack.Format(frames),
+	}
+}
+
+func (e *PanicError) Error() string {
+	if e.Label != "" {
+		return fmt.Sprintf("panic in %s: %v\n%s", e.Label, e.Value, e.Stack)
+	}
+	return fmt.Sprintf("panic: %v\n%s", e.Value, e.Stack)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// goroutineID returns the ID of the calling goroutine, read from the
+// "goroutine 18 [running]:" header of its stack.
+func goroutineID() int64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.ParseInt(string(buf), 10, 64)
+	return id
+}
@@ -0,0 +1,39 @@
+// Description: safego
+// Since: 2026-10-19
+package safego
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPanicError(t *testing.T) {
+	failed := errors.New("failed")
+	var g Group
+	g.GoLabel("worker", func() error {
+		panic(failed)
+	})
+	err := g.Wait()
+	var e *PanicError
+	if !errors.As(err, &e) {
+		t.Fatalf("Expected a PanicError; Got %v", err)
+	}
+	if !errors.Is(err, failed) {
+		t.Errorf("Expected the panic value to be unwrapped; Got %v", err)
+	}
+	if e.Label != "worker" || !strings.HasPrefix(err.Error(), "panic in worker: failed") {
+		t.Errorf("Expected the label; Got %q", err.Error())
+	}
+	if e.Goroutine <= 0 || e.Time.IsZero() {
+		t.Errorf("Expected a goroutine and a time; Got %d, %v", e.Goroutine, e.Time)
+	}
+	if len(e.Frames) == 0 || !strings.HasSuffix(e.Frames[0].File, "panic_test.go") ||
+		e.Frames[0].Source != "panic(failed)" || !strings.HasPrefix(e.Frames[0].Function, "TestPanicError") {
+		t.Errorf("Expected the stack to start at the panic; Got %+v", e.Frames)
+	}
+
+	if e = NewPanicError("value", ""); e.Unwrap() != nil {
+		t.Errorf("Expected nothing to unwrap; Got %v", e.Unwrap())
+	}
+}
@@ -0,0 +1,227 @@
+// Description: safego/pool.go runs tasks with a bounded number of goroutines which survive panics
+// Since: 2026-10-19
+package safego
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrPoolFull is returned by Submit when the queue is full and the
+	// pool does not block.
+	ErrPoolFull = errors.New("safego: pool queue is full")
+	// ErrPoolClosed is returned by Submit after Shutdown.
+	ErrPoolClosed = errors.New("safego: pool is shut down")
+)
+
+// PoolOptions configures a Pool.
+type PoolOptions struct {
+	// Workers is the number of workers which always run, at least 1.
+	Workers int
+	// MaxWorkers is the number of workers the pool grows to while tasks
+	// are waiting. The extra workers exit after IdleTimeout without a
+	// task. It defaults to Workers.
+	MaxWorkers int
+	// IdleTimeout defaults to a minute.
+	IdleTimeout time.Duration
+	// QueueSize is the number of tasks which wait for a worker.
+	QueueSize int
+	// Block makes Submit wait for room in a full queue. By default it
+	// returns ErrPoolFull.
+	Block bool
+	// Handler is called with the value of a panicking task. It defaults
+	// to DefaultHandler.
+	Handler Handler
+}
+
+// PoolStats is a snapshot of the state of a Pool.
+type PoolStats struct {
+	Workers   int64 // running workers
+	Active    int64 // workers running a task
+	Queued    int64 // tasks waiting for a worker
+	Completed int64 // tasks which returned or panicked
+	Panicked  int64
+	Rejected  int64
+}
+
+// Pool runs tasks with a bounded number of goroutines, unlike Go, which
+// starts one per call. A panicking task is recovered like with Go and
+// does not stop its worker.
+type Pool struct {
+	opts  PoolOptions
+	tasks chan func()
+
+	mu     sync.Mutex
+	closed bool
+	// quit is closed by Shutdown to stop the blocked submitters. The
+	// queue is closed once they are gone.
+	quit       chan struct{}
+	submitters sync.WaitGroup
+	wg         sync.WaitGroup
+
+	workers   int64
+	pending   int64 // submitted tasks which did not complete
+	active    int64
+	completed int64
+	panicked  int64
+	rejected  int64
+}
+
+func NewPool(opts PoolOptions) *Pool {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if opts.MaxWorkers < opts.Workers {
+		opts.MaxWorkers = opts.Workers
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = time.Minute
+	}
+	if opts.Handler == nil {
+		opts.Handler = DefaultHandler
+	}
+	p := &Pool{
+		opts:  opts,
+		tasks: make(chan func(), opts.QueueSize),
+		quit:  make(chan struct{}),
+	}
+	p.workers = int64(opts.Workers)
+	p.wg.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go p.work(false)
+	}
+	return p
+}
+
+// Submit queues f to be run by a worker.
+func (p *Pool) Submit(f func()) error {
+	return p.SubmitContext(context.Background(), f)
+}
+
+// SubmitContext is Submit, but gives up waiting for room in the queue when
+// ctx is done.
+func (p *Pool) SubmitContext(ctx context.Context, f func()) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	p.submitters.Add(1)
+	p.mu.Unlock()
+	defer p.submitters.Done()
+
+	n := atomic.AddInt64(&p.pending, 1)
+	if workers := atomic.LoadInt64(&p.workers); n > workers && !p.grow() &&
+		n-workers > int64(p.opts.QueueSize) && !p.opts.Block {
+		atomic.AddInt64(&p.pending, -1)
+		atomic.AddInt64(&p.rejected, 1)
+		return ErrPoolFull
+	}
+	select {
+	case p.tasks <- f:
+		return nil
+	case <-ctx.Done():
+		atomic.AddInt64(&p.pending, -1)
+		atomic.AddInt64(&p.rejected, 1)
+		return ctx.Err()
+	case <-p.quit:
+		atomic.AddInt64(&p.pending, -1)
+		atomic.AddInt64(&p.rejected, 1)
+		return ErrPoolClosed
+	}
+}
+
+// grow starts an extra worker unless there are MaxWorkers already.
+func (p *Pool) grow() bool {
+	for {
+		n := atomic.LoadInt64(&p.workers)
+		if n >= int64(p.opts.MaxWorkers) {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&p.workers, n, n+1) {
+			p.wg.Add(1)
+			go p.work(true)
+			return true
+		}
+	}
+}
+
+// work runs tasks until the queue is closed and drained, or, for an extra
+// worker, until it was idle for IdleTimeout.
+func (p *Pool) work(extra bool) {
+	defer p.wg.Done()
+	defer atomic.AddInt64(&p.workers, -1)
+	var timeout <-chan time.Time
+	for {
+		if extra {
+			timeout = time.After(p.opts.IdleTimeout)
+		}
+		select {
+		case f, ok := <-p.tasks:
+			if !ok {
+				return
+			}
+			p.run(f)
+		case <-timeout:
+			return
+		}
+	}
+}
+
+func (p *Pool) run(f func()) {
+	atomic.AddInt64(&p.active, 1)
+	defer func() {
+		if r := recover(); r != nil {
+			atomic.AddInt64(&p.panicked, 1)
+			p.opts.Handler(r)
+		}
+		atomic.AddInt64(&p.active, -1)
+		atomic.AddInt64(&p.pending, -1)
+		atomic.AddInt64(&p.completed, 1)
+	}()
+	f()
+}
+
+// Shutdown stops accepting tasks and waits until the queued tasks have
+// run, or until ctx is done, which it returns the error of. The workers
+// keep draining the queue in the latter case. Submit calls blocked on a
+// full queue return ErrPoolClosed.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.quit)
+		go func() {
+			p.submitters.Wait()
+			close(p.tasks)
+		}()
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) Stats() PoolStats {
+	return PoolStats{
+		Workers:   atomic.LoadInt64(&p.workers),
+		Active:    atomic.LoadInt64(&p.active),
+		Queued:    int64(len(p.tasks)),
+		Completed: atomic.LoadInt64(&p.completed),
+		Panicked:  atomic.LoadInt64(&p.panicked),
+		Rejected:  atomic.LoadInt64(&p.rejected),
+	}
+}
@@ -0,0 +1,148 @@
+// Description: safego
+// Since: 2026-10-19
+package safego
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	var recovered int64
+	p := NewPool(PoolOptions{Workers: 2, QueueSize: 100, Handler: func(err interface{}) {
+		atomic.AddInt64(&recovered, 1)
+	}})
+	var ran int64
+	for i := 0; i < 50; i++ {
+		i := i
+		if err := p.Submit(func() {
+			if i%10 == 0 {
+				panic("OMG!")
+			}
+			atomic.AddInt64(&ran, 1)
+		}); err != nil {
+			t.Fatalf("Error submitting task: %v", err)
+		}
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Error shutting down: %v", err)
+	}
+	stats := p.Stats()
+	if ran != 45 || recovered != 5 || stats.Panicked != 5 || stats.Completed != 50 || stats.Workers != 0 {
+		t.Errorf("Expected 45 tasks and 5 panics; Got %d, %d, %+v", ran, recovered, stats)
+	}
+	if err := p.Submit(func() {}); err != ErrPoolClosed {
+		t.Errorf("Expected ErrPoolClosed; Got %v", err)
+	}
+}
+
+func TestPoolFull(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	p := NewPool(PoolOptions{Workers: 1, QueueSize: 1})
+	p.Submit(func() {
+		close(started)
+		<-release
+	})
+	<-started
+	if err := p.Submit(func() {}); err != nil {
+		t.Fatalf("Error submitting task: %v", err)
+	}
+	if err := p.Submit(func() {}); err != ErrPoolFull {
+		t.Errorf("Expected ErrPoolFull; Got %v", err)
+	}
+	if stats := p.Stats(); stats.Workers != 1 || stats.Active != 1 || stats.Queued != 1 || stats.Rejected != 1 {
+		t.Errorf("Expected a busy worker and a queued task; Got %+v", stats)
+	}
+
+	// Shutdown gives up when the tasks do not finish in time.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded; Got %v", err)
+	}
+	close(release)
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Errorf("Error shutting down: %v", err)
+	}
+	if stats := p.Stats(); stats.Queued != 0 || stats.Completed != 2 {
+		t.Errorf("Expected the queue to be drained; Got %+v", stats)
+	}
+}
+
+func TestPoolGrow(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	p := NewPool(PoolOptions{Workers: 1, MaxWorkers: 3, IdleTimeout: 10 * time.Millisecond})
+	for i := 0; i < 3; i++ {
+		if err := p.Submit(func() {
+			started <- struct{}{}
+			<-release
+		}); err != nil {
+			t.Fatalf("Error submitting task: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+	if err := p.Submit(func() {}); err != ErrPoolFull {
+		t.Errorf("Expected ErrPoolFull; Got %v", err)
+	}
+	close(release)
+
+	// The extra workers exit when they are idle.
+	for i := 0; i < 100 && p.Stats().Workers > 1; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stats := p.Stats(); stats.Workers != 1 {
+		t.Errorf("Expected 1 worker; Got %+v", stats)
+	}
+	p.Shutdown(context.Background())
+}
+
+func TestPoolBlock(t *testing.T) {
+	release := make(chan struct{})
+	p := NewPool(PoolOptions{Workers: 1, Block: true})
+	p.Submit(func() { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.SubmitContext(ctx, func() {}); err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded; Got %v", err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	if err := p.Submit(func() {}); err != nil {
+		t.Errorf("Expected the task to be queued once the worker is free; Got %v", err)
+	}
+	p.Shutdown(context.Background())
+}
+
+func TestPoolShutdownBlocked(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	p := NewPool(PoolOptions{Workers: 1, Block: true})
+	p.Submit(func() { <-release })
+	submitted := make(chan error)
+	go func() { submitted <- p.Submit(func() {}) }()
+	time.Sleep(10 * time.Millisecond)
+
+	// The worker is stuck, so Shutdown gives up, but it does not wait for
+	// the blocked Submit.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := p.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded; Got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Expected Shutdown to return after 50ms; Got %v", d)
+	}
+	if err := <-submitted; err != ErrPoolClosed {
+		t.Errorf("Expected ErrPoolClosed; Got %v", err)
+	}
+}
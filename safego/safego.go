@@ -24,6 +24,17 @@ with safego, you can replace with:
 	})
 	http.ListenAndServe(":8080", nil)
 
+Go starts a goroutine per call. To bound their number, run the functions in a Pool:
+
+	pool := safego.NewPool(safego.PoolOptions{Workers: 16, QueueSize: 1000})
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if err := pool.Submit(func() {
+			panic("OMG!")
+		}); err == safego.ErrPoolFull {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+		}
+	})
+
  */
 // Author: ZHU HAIHUA
 // Since: 2016-03-22 15:57
@@ -31,14 +42,16 @@ package safego
 
 import (
 	"fmt"
-	"github.com/kimiazhu/golib/stack"
 	"os"
 )
 
 type Handler func(err interface{})
 
+// DefaultHandler prints the panic and its stack to stderr. A Handler can
+// call NewPanicError to get them as structured fields.
 var DefaultHandler = func(err interface{}) {
-	fmt.Fprintf(os.Stderr, "recovered: %s\n%s", err, stack.CallStack(3))
+	e := NewPanicError(err, "")
+	fmt.Fprintf(os.Stderr, "recovered: %s (goroutine %d)\n%s", err, e.Goroutine, e.Stack)
 }
 
 // Go run the f with a goroutine and keep it away from panic.
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gorilla/sessions"
+	"time"
+)
+
+// ErrNotSupported is returned by the administration methods of Store when
+// the backend cannot enumerate its sessions.
+var ErrNotSupported = errors.New("operation not supported by the session backend")
+
+// Lister is implemented by backends which can enumerate their sessions.
+type Lister interface {
+	// Count returns the number of sessions which have not expired.
+	Count(ctx context.Context) (int64, error)
+	// List returns up to limit records following cursor, which is "" for
+	// the first page, and the cursor of the next page, which is "" after
+	// the last one. limit is positive. Records deleted or inserted while
+	// listing may or may not be returned, expired records may be returned.
+	List(ctx context.Context, cursor string, limit int) ([]*Record, string, error)
+}
+
+// Lookup returns the session named name with the given ID without an HTTP
+// request, e.g. for a support tool. It returns ErrNotFound if there is no
+// such session or it has expired.
+func (s *Store) Lookup(ctx context.Context, name, id string) (*sessions.Session, error) {
+	session := s.newSession(name)
+	session.ID = id
+	if err := s.load(ctx, session); err != nil {
+		if err == errExpired {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	session.IsNew = false
+	return session, nil
+}
+
+// Revoke deletes the session with the given ID. The next request using it
+// gets a new session.
+func (s *Store) Revoke(ctx context.Context, id string) error {
+	return s.Backend.Delete(ctx, id)
+}
+
+// Count returns the number of active sessions and reports it to the
+// Observer.
+func (s *Store) Count(ctx context.Context) (int64, error) {
+	lister, ok := s.Backend.(Lister)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	n, err := lister.Count(ctx)
+	if err == nil && s.Observer != nil {
+		s.Observer.Counted(n)
+	}
+	return n, err
+}
+
+// List returns up to limit active sessions named name following cursor,
+// and the cursor of the next page. Pass "" to get the first page, an
+// empty next cursor means there are no more pages. Sessions whose values
+// cannot be decoded with name are skipped, so a page may be short.
+func (s *Store) List(ctx context.Context, name, cursor string, limit int) ([]*sessions.Session, string, error) {
+	lister, ok := s.Backend.(Lister)
+	if !ok {
+		return nil, "", ErrNotSupported
+	}
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("session list limit must be positive, got %d", limit)
+	}
+	recs, next, err := lister.List(ctx, cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	list := make([]*sessions.Session, 0, len(recs))
+	for _, rec := range recs {
+		if s.Expiration.expired(rec, time.Now()) {
+			continue
+		}
+		session := s.newSession(name)
+		session.ID = rec.ID
+		if err := s.decodeRecord(rec, session); err != nil {
+			continue
+		}
+		session.IsNew = false
+		list = append(list, session)
+	}
+	return list, next, nil
+}
+
+// RevokeMatching deletes every active session named name for which match
+// returns true, e.g. all sessions of a user after a password reset, and
+// returns the number of deleted sessions. It reads the whole backend, so
+// prefer an indexed lookup where the backend has one.
+func (s *Store) RevokeMatching(ctx context.Context, name string, match func(*sessions.Session) bool) (int64, error) {
+	var n int64
+	cursor := ""
+	for {
+		list, next, err := s.List(ctx, name, cursor, 500)
+		if err != nil {
+			return n, err
+		}
+		for _, session := range list {
+			if !match(session) {
+				continue
+			}
+			if err = s.Revoke(ctx, session.ID); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if next == "" {
+			return n, nil
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned by Backend.Load when there is no session
+	// with the given ID.
+	ErrNotFound = errors.New("session not found")
+	// ErrDuplicateID is returned by Backend.Insert when a session with
+	// the same ID already exists.
+	ErrDuplicateID = errors.New("duplicate session id")
+	// ErrVersionConflict is returned by Swapper.Swap when the record was
+	// changed since it was loaded.
+	ErrVersionConflict = errors.New("session version conflict")
+)
+
+// Record is a session as it is persisted by a Backend. Data holds the
+// encoded session values, UserID the value of Store.UserKey if any.
+type Record struct {
+	ID         string
+	Data       []byte
+	UserID     string
+	CreatedOn  time.Time
+	ModifiedOn time.Time
+	ExpiresOn  time.Time
+	// Version is incremented by every save, starting from 1.
+	Version int64
+}
+
+// Superseded is the Version of a record which Store.Regenerate moved to a
+// new ID and keeps for RegenerateGrace. Such a record still loads, but
+// Store never saves it again, so it expires with the grace period.
+const Superseded = -1
+
+// Backend persists session records. Cookies, expiry and encoding are
+// handled by Store, so a backend only needs to store records by ID.
+type Backend interface {
+	// Load returns the record of id, or ErrNotFound.
+	Load(ctx context.Context, id string) (*Record, error)
+	// Insert stores a new record, or returns ErrDuplicateID if the ID is
+	// already used.
+	Insert(ctx context.Context, rec *Record) error
+	// Save replaces the record with the ID of rec, or returns ErrNotFound
+	// if there is none. It never stores a new record, so that a session
+	// which was deleted, e.g. revoked, while a request used it stays
+	// deleted.
+	Save(ctx context.Context, rec *Record) error
+	// Delete removes the record of id. Deleting a missing record is not
+	// an error.
+	Delete(ctx context.Context, id string) error
+}
+
+// UserIndexer is implemented by backends which index sessions by
+// Record.UserID.
+type UserIndexer interface {
+	// ListByUser returns the records of userID, oldest first. Expired
+	// records may be returned.
+	ListByUser(ctx context.Context, userID string) ([]*Record, error)
+	// ListIDsByUser returns the unexpired records of userID, oldest
+	// first. Only their ID and CreatedOn need to be set.
+	ListIDsByUser(ctx context.Context, userID string) ([]*Record, error)
+	// DeleteByUser deletes the records of userID and returns their number.
+	DeleteByUser(ctx context.Context, userID string) (int64, error)
+}
+
+// Mover is implemented by backends which can give a record a new ID in one
+// step. Store.Regenerate falls back to Insert and Delete otherwise.
+type Mover interface {
+	// Move inserts rec, which has a new ID, and deletes the record of
+	// oldID. If expireOld is not zero, the old record is kept until then
+	// instead, with Version Superseded. Like Insert it returns
+	// ErrDuplicateID if the ID is used.
+	Move(ctx context.Context, oldID string, rec *Record, expireOld time.Time) error
+}
+
+// Swapper is implemented by backends which support optimistic concurrency,
+// see Store.OnConflict.
+type Swapper interface {
+	// Swap stores rec if the stored record has the given version. It
+	// returns ErrVersionConflict if it has another version and ErrNotFound
+	// if there is no record.
+	Swap(ctx context.Context, rec *Record, version int64) error
+}
+
+// PrimaryLoader is implemented by backends whose Load may return an
+// outdated record, e.g. from a read replica. Store uses it where it needs
+// the current record, to resolve conflicts.
+type PrimaryLoader interface {
+	// LoadPrimary is Load, but reads the authoritative copy.
+	LoadPrimary(ctx context.Context, id string) (*Record, error)
+}
+
+// Toucher is implemented by backends which can extend the expiry of a
+// record without writing its data.
+type Toucher interface {
+	// Touch sets the times of the record of id, or returns ErrNotFound.
+	Touch(ctx context.Context, id string, modifiedOn, expiresOn time.Time) error
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"github.com/golang/snappy"
+	"io"
+)
+
+// Compression selects how session values are compressed before they are
+// signed and encrypted. Records are marked with the algorithm, so every
+// record can be read whatever the current setting is.
+type Compression int
+
+const (
+	NoCompression Compression = iota
+	// Gzip compresses best.
+	Gzip
+	// Snappy is much faster than Gzip but compresses less.
+	Snappy
+)
+
+// compressedMagic starts the records of each algorithm. Like
+// encryptedMagic, it cannot start the output of securecookie or of the
+// serializers.
+var compressedMagic = map[Compression][]byte{
+	Gzip:   []byte("\x00gz1"),
+	Snappy: []byte("\x00snp1"),
+}
+
+// ErrTooLarge is returned when the data of a session exceeds
+// Store.MaxSize. Save returns it as a *SizeError.
+var ErrTooLarge = errors.New("session too large")
+
+// SizeError is returned by Save when the stored data of a session would
+// exceed Store.MaxSize.
+type SizeError struct {
+	Name    string
+	Size    int
+	MaxSize int
+}
+
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("session %s is %d bytes, the limit is %d", e.Name, e.Size, e.MaxSize)
+}
+
+func (e *SizeError) Unwrap() error {
+	return ErrTooLarge
+}
+
+func compress(c Compression, data []byte) ([]byte, error) {
+	switch c {
+	case Gzip:
+		buf := new(bytes.Buffer)
+		w := gzip.NewWriter(buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case Snappy:
+		return snappy.Encode(nil, data), nil
+	}
+	return nil, fmt.Errorf("unknown session compression %d", c)
+}
+
+func decompress(c Compression, data []byte) ([]byte, error) {
+	switch c {
+	case Gzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case Snappy:
+		return snappy.Decode(nil, data)
+	}
+	return nil, fmt.Errorf("unknown session compression %d", c)
+}
+
+// splitCompressed returns the algorithm of a compressed record and the
+// data following the marker.
+func splitCompressed(data []byte) (Compression, []byte, bool) {
+	for c, magic := range compressedMagic {
+		if bytes.HasPrefix(data, magic) {
+			return c, data[len(magic):], true
+		}
+	}
+	return NoCompression, data, false
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"errors"
+	"github.com/gorilla/sessions"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	big := strings.Repeat("session data ", 200)
+	for _, plaintext := range []bool{false, true} {
+		for _, c := range []Compression{NoCompression, Gzip, Snappy} {
+			store := NewStore(nil, "/", 3600, []byte("secret-key"))
+			store.Plaintext = plaintext
+			store.MaxSize = 0
+			src := sessions.NewSession(store, "session-key")
+			src.Values["big"] = big
+
+			// Written uncompressed, read with compression.
+			uncompressed, err := store.encode(src)
+			if err != nil {
+				t.Fatalf("Error encoding session: %v", err)
+			}
+			store.Compression = c
+			data, err := store.encode(src)
+			if err != nil {
+				t.Fatalf("%d: Error encoding session: %v", c, err)
+			}
+			if c != NoCompression && len(data) >= len(uncompressed) {
+				t.Errorf("%d: Expected less than %d bytes; Got %d", c, len(uncompressed), len(data))
+			}
+			for _, d := range [][]byte{uncompressed, data} {
+				dst := sessions.NewSession(store, "session-key")
+				if err = store.decode(d, dst); err != nil {
+					t.Fatalf("%d: Error decoding session: %v", c, err)
+				}
+				if dst.Values["big"] != big {
+					t.Errorf("%d: Expected the value to survive; Got %v", c, dst.Values["big"])
+				}
+			}
+		}
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	store := NewStore(nil, "/", 3600, []byte("secret-key"))
+	store.MaxSize = 1000
+	session := sessions.NewSession(store, "session-key")
+	session.Values["big"] = strings.Repeat("x", 2000)
+	_, err := store.encode(session)
+	var sizeErr *SizeError
+	if !errors.As(err, &sizeErr) || !errors.Is(err, ErrTooLarge) || sizeErr.MaxSize != 1000 {
+		t.Fatalf("Expected a SizeError; Got %v", err)
+	}
+
+	// Compressed, it fits.
+	store.Compression = Gzip
+	if _, err = store.encode(session); err != nil {
+		t.Errorf("Expected the compressed session to fit; Got %v", err)
+	}
+}
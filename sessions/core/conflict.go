@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"github.com/gorilla/sessions"
+)
+
+// ConflictStrategy decides what Save does when the session was saved by
+// another request since it was loaded.
+type ConflictStrategy int
+
+const (
+	// LastWriteWins overwrites the changes of the other request.
+	LastWriteWins ConflictStrategy = iota
+	// FailOnConflict returns a *ConflictError.
+	FailOnConflict
+	// MergeOnConflict calls Store.Merge with the stored session and saves
+	// the result, or returns a *ConflictError if Merge is nil or the
+	// session keeps changing.
+	MergeOnConflict
+)
+
+// mergeRetries bounds the attempts of MergeOnConflict.
+const mergeRetries = 3
+
+// ConflictError is returned by Save when the session was saved by another
+// request since it was loaded.
+type ConflictError struct {
+	Name string
+}
+
+func (e *ConflictError) Error() string {
+	return "session " + e.Name + " was saved by another request"
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrVersionConflict
+}
+
+// swap stores rec if the record still has version, resolving conflicts
+// according to OnConflict. It returns the stored record, or ErrNotFound if
+// the record was deleted.
+func (s *Store) swap(ctx context.Context, session *sessions.Session, rec *Record, version int64) (*Record, error) {
+	swapper, ok := s.Backend.(Swapper)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	for retry := 0; ; retry++ {
+		err := swapper.Swap(ctx, rec, version)
+		if err != ErrVersionConflict {
+			return rec, err
+		}
+		if s.OnConflict != MergeOnConflict || s.Merge == nil || retry == mergeRetries {
+			return nil, &ConflictError{session.Name()}
+		}
+
+		stored, storedRec, err := s.loadStored(ctx, session)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.Merge(stored, session); err != nil {
+			return nil, err
+		}
+		m, _ := s.getMeta(session)
+		m.version = storedRec.Version
+		if rec, err = s.newRecord(session, m); err != nil {
+			return nil, err
+		}
+		version = storedRec.Version
+	}
+}
+
+// loadStored returns the session as it is stored now.
+func (s *Store) loadStored(ctx context.Context, session *sessions.Session) (*sessions.Session, *Record, error) {
+	var rec *Record
+	var err error
+	if primary, ok := s.Backend.(PrimaryLoader); ok {
+		rec, err = primary.LoadPrimary(ctx, session.ID)
+	} else {
+		rec, err = s.Backend.Load(ctx, session.ID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	stored := s.newSession(session.Name())
+	stored.ID = session.ID
+	stored.IsNew = false
+	if err = s.decodeRecord(rec, stored); err != nil {
+		return nil, nil, err
+	}
+	return stored, rec, nil
+}
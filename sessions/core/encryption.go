@@ -0,0 +1,165 @@
+package core
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// encryptedMagic starts every encrypted record. It cannot start the
+// output of securecookie or of the serializers.
+var encryptedMagic = []byte("\x00enc1")
+
+var (
+	// ErrUnknownKey is returned when a record was encrypted with a key
+	// which is not in the Keyring.
+	ErrUnknownKey = errors.New("session encrypted with an unknown key")
+	errCiphertext = errors.New("malformed encrypted session")
+)
+
+// Key is a key encryption key of a Keyring. Secret must be 16, 24 or 32
+// bytes long to select AES-128, AES-192 or AES-256.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Keyring encrypts the stored session data with AES-GCM, independent of
+// the signing of the cookies.
+//
+// Every record is encrypted with a random data key, which is in turn
+// encrypted with the first key of the keyring and stored in the record
+// together with the ID of that key. The other keys only decrypt, so a key
+// is rotated by putting a new key first. Records of an older key are
+// encrypted with the new one when they are loaded.
+type Keyring struct {
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+func NewKeyring(keys ...Key) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no encryption key")
+	}
+	k := &Keyring{
+		primary: keys[0].ID,
+		aeads:   make(map[string]cipher.AEAD),
+	}
+	for _, key := range keys {
+		if len(key.ID) == 0 || len(key.ID) > 255 {
+			return nil, fmt.Errorf("invalid encryption key ID %q", key.ID)
+		}
+		if _, ok := k.aeads[key.ID]; ok {
+			return nil, fmt.Errorf("duplicated encryption key ID %q", key.ID)
+		}
+		aead, err := newGCM(key.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %v", key.ID, err)
+		}
+		k.aeads[key.ID] = aead
+	}
+	return k, nil
+}
+
+// Encrypt returns data encrypted with the primary key as
+// magic | len(key ID) | key ID | encrypted data key | nonce | ciphertext.
+func (k *Keyring) Encrypt(data []byte) ([]byte, error) {
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+	wrapped, err := seal(k.aeads[k.primary], dataKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := seal(aead, data)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(encryptedMagic)+1+len(k.primary)+len(wrapped)+len(sealed))
+	out = append(out, encryptedMagic...)
+	out = append(out, byte(len(k.primary)))
+	out = append(out, k.primary...)
+	out = append(out, wrapped...)
+	return append(out, sealed...), nil
+}
+
+// Decrypt returns the data of a record returned by Encrypt.
+func (k *Keyring) Decrypt(encrypted []byte) ([]byte, error) {
+	keyID, rest, err := splitKeyID(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	kek, ok := k.aeads[keyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	n := kek.NonceSize() + 32 + kek.Overhead()
+	if len(rest) < n {
+		return nil, errCiphertext
+	}
+	dataKey, err := open(kek, rest[:n])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return open(aead, rest[n:])
+}
+
+// stale reports whether data is not encrypted with the primary key.
+func (k *Keyring) stale(data []byte) bool {
+	keyID, _, err := splitKeyID(data)
+	return err != nil || keyID != k.primary
+}
+
+// isEncrypted reports whether data was returned by Keyring.Encrypt.
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedMagic)
+}
+
+func splitKeyID(data []byte) (string, []byte, error) {
+	if !isEncrypted(data) || len(data) <= len(encryptedMagic) {
+		return "", nil, errCiphertext
+	}
+	data = data[len(encryptedMagic):]
+	n := int(data[0])
+	if len(data) < 1+n {
+		return "", nil, errCiphertext
+	}
+	return string(data[1 : 1+n]), data[1+n:], nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal returns nonce | ciphertext.
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errCiphertext
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+}
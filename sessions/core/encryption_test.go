@@ -0,0 +1,97 @@
+package core
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKeyring(t *testing.T) {
+	old, err := NewKeyring(Key{"2025", bytes.Repeat([]byte("a"), 32)})
+	if err != nil {
+		t.Fatalf("Error creating keyring: %v", err)
+	}
+	encrypted, err := old.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Error encrypting: %v", err)
+	}
+	if bytes.Contains(encrypted, []byte("secret")) {
+		t.Errorf("Expected the data to be encrypted")
+	}
+
+	rotated, err := NewKeyring(Key{"2026", bytes.Repeat([]byte("b"), 32)}, Key{"2025", bytes.Repeat([]byte("a"), 32)})
+	if err != nil {
+		t.Fatalf("Error creating keyring: %v", err)
+	}
+	if !rotated.stale(encrypted) {
+		t.Errorf("Expected data of the old key to be stale")
+	}
+	data, err := rotated.Decrypt(encrypted)
+	if err != nil || string(data) != "secret" {
+		t.Fatalf("Expected secret; Got %q, %v", data, err)
+	}
+	if encrypted, err = rotated.Encrypt(data); err != nil {
+		t.Fatalf("Error encrypting: %v", err)
+	}
+	if rotated.stale(encrypted) {
+		t.Errorf("Expected data of the primary key not to be stale")
+	}
+	if _, err = old.Decrypt(encrypted); err != ErrUnknownKey {
+		t.Errorf("Expected ErrUnknownKey; Got %v", err)
+	}
+
+	encrypted[len(encrypted)-1] ^= 1
+	if _, err = rotated.Decrypt(encrypted); err == nil {
+		t.Errorf("Expected an error for tampered data")
+	}
+	if _, err = NewKeyring(Key{"short", []byte("too short")}); err == nil {
+		t.Errorf("Expected an error for an invalid key")
+	}
+}
+
+func TestStoreEncryption(t *testing.T) {
+	store, backend := newTestStore(3600)
+
+	// A session stored before encryption was enabled.
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	rsp := httptest.NewRecorder()
+	session, _ := store.New(req, "session-key")
+	session.Values["email"] = "alice@example.com"
+	if err := store.Save(req, rsp, session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+
+	keyring, err := NewKeyring(Key{ID: "1", Secret: []byte("0123456789abcdef0123456789abcdef")})
+	if err != nil {
+		t.Fatalf("Error creating keyring: %v", err)
+	}
+	store.Keyring = keyring
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Add("Cookie", rsp.Header()["Set-Cookie"][0])
+	session, err = store.New(req, "session-key")
+	if err != nil || session.IsNew {
+		t.Fatalf("Expected the unencrypted session to load; Got %v", err)
+	}
+	if session.Values["email"] != "alice@example.com" {
+		t.Errorf("Expected alice@example.com; Got %v", session.Values["email"])
+	}
+
+	// Loading it has encrypted the record.
+	data := backend.get(session.ID).Data
+	if bytes.Contains(data, []byte("alice")) || !bytes.HasPrefix(data, []byte("\x00enc1")) {
+		t.Errorf("Expected the record to be encrypted; Got %q", data)
+	}
+	if rec := backend.get(session.ID); rec.Version != 2 {
+		t.Errorf("Expected version 2; Got %d", rec.Version)
+	}
+	// The session knows the new version, so saving it is no conflict.
+	store.OnConflict = FailOnConflict
+	session.Values["email"] = "bob@example.com"
+	if err = store.Save(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	if session, err = store.New(req, "session-key"); err != nil || session.Values["email"] != "bob@example.com" {
+		t.Errorf("Expected the encrypted session to load; Got %v, %v", session.Values, err)
+	}
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"time"
+)
+
+// Expiration decides when sessions expire. The zero value keeps a session
+// for Options.MaxAge after it was last saved, as before.
+//
+// For a 30 minute idle timeout and a hard limit of 12 hours:
+//
+//	store.Expiration = core.Expiration{
+//		IdleTimeout: 30 * time.Minute,
+//		MaxLifetime: 12 * time.Hour,
+//	}
+type Expiration struct {
+	// IdleTimeout expires a session which was not saved for this long.
+	// Every save slides the expiry forward.
+	IdleTimeout time.Duration
+	// MaxLifetime expires a session this long after it was created, no
+	// matter how often it is saved. Zero means no limit.
+	MaxLifetime time.Duration
+}
+
+// expiresOn returns the expiry of a session created on createdOn and
+// saved now. Without an idle timeout, the session is kept for maxAge as
+// usual, but not past MaxLifetime.
+func (e Expiration) expiresOn(createdOn, now time.Time, maxAge int) time.Time {
+	if maxAge < 0 {
+		// the session is being deleted
+		return now
+	}
+	if e.IdleTimeout <= 0 && e.MaxLifetime <= 0 {
+		return now.Add(time.Second * time.Duration(maxAge))
+	}
+	var expiresOn time.Time
+	if e.IdleTimeout > 0 {
+		expiresOn = now.Add(e.IdleTimeout)
+	} else if maxAge > 0 {
+		expiresOn = now.Add(time.Second * time.Duration(maxAge))
+	}
+	if e.MaxLifetime > 0 {
+		limit := createdOn.Add(e.MaxLifetime)
+		if expiresOn.IsZero() || limit.Before(expiresOn) {
+			expiresOn = limit
+		}
+	}
+	return expiresOn
+}
+
+// expired reports whether rec has expired at now. The limits are checked
+// against the current policy as well, so that lowering them takes effect
+// for sessions which were saved before.
+func (e Expiration) expired(rec *Record, now time.Time) bool {
+	if rec.ExpiresOn.Before(now) {
+		return true
+	}
+	if e.MaxLifetime > 0 && now.After(rec.CreatedOn.Add(e.MaxLifetime)) {
+		return true
+	}
+	return e.IdleTimeout > 0 && now.After(rec.ModifiedOn.Add(e.IdleTimeout))
+}
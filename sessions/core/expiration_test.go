@@ -0,0 +1,80 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExpiration(t *testing.T) {
+	now := time.Now()
+	created := now.Add(-11*time.Hour - 45*time.Minute)
+	tests := []struct {
+		name string
+		e    Expiration
+		want time.Time
+	}{
+		{"max age", Expiration{}, now.Add(time.Hour)},
+		{"sliding", Expiration{IdleTimeout: 30 * time.Minute}, now.Add(30 * time.Minute)},
+		{"absolute", Expiration{MaxLifetime: 12 * time.Hour}, created.Add(12 * time.Hour)},
+		{"max age within absolute", Expiration{MaxLifetime: 24 * time.Hour}, now.Add(time.Hour)},
+		{"both", Expiration{IdleTimeout: 30 * time.Minute, MaxLifetime: 12 * time.Hour}, created.Add(12 * time.Hour)},
+	}
+	for _, test := range tests {
+		if got := test.e.expiresOn(created, now, 3600); !got.Equal(test.want) {
+			t.Errorf("%s: Expected %v; Got %v", test.name, test.want, got)
+		}
+	}
+	if got := (Expiration{}).expiresOn(created, now, -1); !got.Equal(now) {
+		t.Errorf("Expected a deleted session to expire now; Got %v", got)
+	}
+
+	// Records saved under a looser policy expire by the current one.
+	rec := &Record{CreatedOn: created, ModifiedOn: now.Add(-time.Hour), ExpiresOn: now.Add(time.Hour)}
+	if (Expiration{}).expired(rec, now) {
+		t.Errorf("Expected the record not to expire by its expires_on")
+	}
+	if !(Expiration{IdleTimeout: 30 * time.Minute}).expired(rec, now) {
+		t.Errorf("Expected the record to expire by the idle timeout")
+	}
+	if !(Expiration{MaxLifetime: 6 * time.Hour}).expired(rec, now) {
+		t.Errorf("Expected the record to expire by the lifetime")
+	}
+}
+
+func TestStoreExpiration(t *testing.T) {
+	store, backend := newTestStore(86400)
+	store.Expiration = Expiration{IdleTimeout: 30 * time.Minute, MaxLifetime: 12 * time.Hour}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	rsp := httptest.NewRecorder()
+	session, _ := store.New(req, "session-key")
+	if err := store.Save(req, rsp, session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	if left := time.Until(ExpiresOn(session)); left <= 0 || left > 30*time.Minute {
+		t.Errorf("Expected to expire within 30 minutes; Got %v", left)
+	}
+	if cookie := rsp.Result().Cookies()[0]; cookie.MaxAge > 1801 {
+		t.Errorf("Expected the cookie to expire with the session; Got MaxAge %d", cookie.MaxAge)
+	}
+
+	// The session was kept alive, but is older than 12 hours.
+	rec := backend.get(session.ID)
+	rec.CreatedOn = time.Now().Add(-13 * time.Hour)
+	backend.set(rec)
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Add("Cookie", rsp.Header()["Set-Cookie"][0])
+	session, err := store.New(req, "session-key")
+	if err != nil {
+		t.Fatalf("Error getting session: %v", err)
+	}
+	if !session.IsNew {
+		t.Errorf("Expected a new session after the lifetime")
+	}
+	if len(session.Values) != 0 {
+		t.Errorf("Expected no values; Got %v", session.Values)
+	}
+}
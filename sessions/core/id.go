@@ -0,0 +1,23 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+)
+
+// sessionIDBytes is the number of random bytes in a session ID. 32 bytes
+// encode to 52 base32 characters.
+const sessionIDBytes = 32
+
+var idEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newSessionID returns a random, unguessable session ID read from
+// crypto/rand.
+func newSessionID() (string, error) {
+	b := make([]byte, sessionIDBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToLower(idEncoding.EncodeToString(b)), nil
+}
@@ -0,0 +1,22 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestNewSessionID(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id, err := newSessionID()
+		if err != nil {
+			t.Fatalf("Error generating session ID: %v", err)
+		}
+		if len(id) != 52 {
+			t.Errorf("Expected 52 characters; Got %d in %q", len(id), id)
+		}
+		if seen[id] {
+			t.Fatalf("Duplicated session ID %q", id)
+		}
+		seen[id] = true
+	}
+}
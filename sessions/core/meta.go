@@ -0,0 +1,62 @@
+package core
+
+import (
+	"github.com/gorilla/sessions"
+	"runtime"
+	"time"
+	"weak"
+)
+
+// meta is what Store remembers of the record a session was loaded from.
+type meta struct {
+	createdOn  time.Time
+	modifiedOn time.Time
+	expiresOn  time.Time
+	version    int64
+	userID     string
+	// data is the stored encoding of the values, see Store.unchanged.
+	data []byte
+}
+
+// getMeta returns the meta of session. It is kept in Store.metas rather
+// than in session.Values, where the application could clear or replace
+// it, and is dropped when the session is garbage collected.
+func (s *Store) getMeta(session *sessions.Session) (meta, bool) {
+	m, ok := s.metas.Load(weak.Make(session))
+	if !ok {
+		return meta{}, false
+	}
+	return m.(meta), true
+}
+
+func (s *Store) setMeta(session *sessions.Session, rec *Record) {
+	key := weak.Make(session)
+	m := meta{rec.CreatedOn, rec.ModifiedOn, rec.ExpiresOn, rec.Version, rec.UserID, rec.Data}
+	if _, loaded := s.metas.Swap(key, m); !loaded {
+		runtime.AddCleanup(session, func(key weak.Pointer[sessions.Session]) {
+			s.metas.Delete(key)
+		}, key)
+	}
+}
+
+// CreatedOn returns when session was created, or the zero time if it was
+// not saved yet.
+func CreatedOn(session *sessions.Session) time.Time {
+	s, ok := session.Store().(*Store)
+	if !ok {
+		return time.Time{}
+	}
+	m, _ := s.getMeta(session)
+	return m.createdOn
+}
+
+// ExpiresOn returns when session expires unless it is saved again, or the
+// zero time if it was not saved yet.
+func ExpiresOn(session *sessions.Session) time.Time {
+	s, ok := session.Store().(*Store)
+	if !ok {
+		return time.Time{}
+	}
+	m, _ := s.getMeta(session)
+	return m.expiresOn
+}
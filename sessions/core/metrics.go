@@ -0,0 +1,121 @@
+package core
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Metrics is an Observer which counts what a store does. Read it with
+// Snapshot, e.g. to publish it to a monitoring system:
+//
+//	metrics := &core.Metrics{}
+//	store.Observer = metrics
+//	...
+//	prev := metrics.Snapshot()
+//	for range time.Tick(time.Minute) {
+//		cur := metrics.Snapshot()
+//		if cur.Sub(prev).DecodeFailureRate() > 0.5 {
+//			// most cookies suddenly fail to decode: were keys rotated?
+//		}
+//		prev = cur
+//	}
+type Metrics struct {
+	loads    [LoadFailed + 1]int64
+	loadTime int64
+
+	saves      int64
+	saveErrors int64
+	saveTime   int64
+
+	failures int64
+	sessions int64
+}
+
+// MetricsSnapshot holds the counters of Metrics at one point in time.
+type MetricsSnapshot struct {
+	// Loads counts the loads by their result.
+	Loads    map[LoadResult]int64
+	LoadTime time.Duration
+
+	Saves      int64
+	SaveErrors int64
+	SaveTime   time.Duration
+
+	// Failures counts the errors which were not returned to any caller.
+	Failures int64
+	// Sessions is the number of active sessions last reported by Count.
+	Sessions int64
+}
+
+func (m *Metrics) Loaded(name string, result LoadResult, d time.Duration) {
+	if result >= 0 && result <= LoadFailed {
+		atomic.AddInt64(&m.loads[result], 1)
+	}
+	atomic.AddInt64(&m.loadTime, int64(d))
+}
+
+func (m *Metrics) Saved(name string, d time.Duration, err error) {
+	atomic.AddInt64(&m.saves, 1)
+	if err != nil {
+		atomic.AddInt64(&m.saveErrors, 1)
+	}
+	atomic.AddInt64(&m.saveTime, int64(d))
+}
+
+func (m *Metrics) Failed(op string, err error) {
+	atomic.AddInt64(&m.failures, 1)
+}
+
+func (m *Metrics) Counted(n int64) {
+	atomic.StoreInt64(&m.sessions, n)
+}
+
+// Snapshot returns the current counters.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	s := MetricsSnapshot{
+		Loads:      make(map[LoadResult]int64, len(m.loads)),
+		LoadTime:   time.Duration(atomic.LoadInt64(&m.loadTime)),
+		Saves:      atomic.LoadInt64(&m.saves),
+		SaveErrors: atomic.LoadInt64(&m.saveErrors),
+		SaveTime:   time.Duration(atomic.LoadInt64(&m.saveTime)),
+		Failures:   atomic.LoadInt64(&m.failures),
+		Sessions:   atomic.LoadInt64(&m.sessions),
+	}
+	for i := range m.loads {
+		s.Loads[LoadResult(i)] = atomic.LoadInt64(&m.loads[i])
+	}
+	return s
+}
+
+// Sub returns the counters which accumulated since prev was taken.
+// Sessions is kept as is.
+func (s MetricsSnapshot) Sub(prev MetricsSnapshot) MetricsSnapshot {
+	d := MetricsSnapshot{
+		Loads:      make(map[LoadResult]int64, len(s.Loads)),
+		LoadTime:   s.LoadTime - prev.LoadTime,
+		Saves:      s.Saves - prev.Saves,
+		SaveErrors: s.SaveErrors - prev.SaveErrors,
+		SaveTime:   s.SaveTime - prev.SaveTime,
+		Failures:   s.Failures - prev.Failures,
+		Sessions:   s.Sessions,
+	}
+	for r, n := range s.Loads {
+		d.Loads[r] = n - prev.Loads[r]
+	}
+	return d
+}
+
+// DecodeFailureRate returns the share of loads which failed because the
+// cookie or the stored session could not be decoded. A jump usually means
+// that keys were rotated without keeping the old ones.
+func (s MetricsSnapshot) DecodeFailureRate() float64 {
+	var total int64
+	for _, n := range s.Loads {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	failed := s.Loads[BadCookie] + s.Loads[BadData] + s.Loads[UnknownKey]
+	return float64(failed) / float64(total)
+}
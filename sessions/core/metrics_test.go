@@ -0,0 +1,57 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	store, _ := newTestStore(3600)
+	metrics := &Metrics{}
+	store.Observer = metrics
+	keyring, _ := NewKeyring(Key{ID: "1", Secret: []byte("0123456789abcdef0123456789abcdef")})
+	store.Keyring = keyring
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	rsp := httptest.NewRecorder()
+	session, _ := store.New(req, "session-key")
+	session.Values["foo"] = "bar"
+	if err := store.Save(req, rsp, session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	cookie := rsp.Header()["Set-Cookie"][0]
+
+	load := func(cookie string) {
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		req.Header.Add("Cookie", cookie)
+		store.New(req, "session-key")
+	}
+	load(cookie)
+	load("session-key=tampered")
+	// The key the session was encrypted with is dropped.
+	store.Keyring, _ = NewKeyring(Key{ID: "2", Secret: []byte("abcdef0123456789abcdef0123456789")})
+	load(cookie)
+	store.Backend.Delete(context.Background(), session.ID)
+	load(cookie)
+	if _, err := store.Count(context.Background()); err != nil {
+		t.Fatalf("Error counting sessions: %v", err)
+	}
+
+	snap := metrics.Snapshot()
+	for result, n := range map[LoadResult]int64{Loaded: 1, BadCookie: 1, UnknownKey: 1, Missing: 1, Expired: 0} {
+		if snap.Loads[result] != n {
+			t.Errorf("Expected %d %s loads; Got %d", n, result, snap.Loads[result])
+		}
+	}
+	if snap.Saves != 1 || snap.SaveErrors != 0 || snap.Sessions != 0 {
+		t.Errorf("Expected 1 save and 0 sessions; Got %+v", snap)
+	}
+	if rate := snap.DecodeFailureRate(); rate != 0.5 {
+		t.Errorf("Expected a decode failure rate of 0.5; Got %v", rate)
+	}
+	if d := snap.Sub(snap); d.Saves != 0 || d.DecodeFailureRate() != 0 {
+		t.Errorf("Expected no change; Got %+v", d)
+	}
+}
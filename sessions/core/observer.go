@@ -0,0 +1,77 @@
+package core
+
+import (
+	"log"
+	"time"
+)
+
+// Observer is notified of what a Store does, e.g. to export metrics or to
+// alert when cookies stop decoding after a key rotation. Its methods are
+// called synchronously by the request and must be safe for concurrent use.
+type Observer interface {
+	// Loaded is called after the session named name was looked up for a
+	// request carrying its cookie, or by Lookup.
+	Loaded(name string, result LoadResult, d time.Duration)
+	// Saved is called after the session named name was saved or
+	// regenerated. err is the error returned to the caller.
+	Saved(name string, d time.Duration, err error)
+	// Failed reports errors which are not returned to any caller, e.g.
+	// of the background cleanup. op describes what failed.
+	Failed(op string, err error)
+	// Counted reports the number of active sessions, see Store.Count.
+	Counted(n int64)
+}
+
+// LoadResult is the outcome of loading a session.
+type LoadResult int
+
+const (
+	// Loaded means the session was found and decoded.
+	Loaded LoadResult = iota
+	// Missing means the cookie is valid but there is no such session.
+	Missing
+	// Expired means the session has expired.
+	Expired
+	// BadCookie means the cookie could not be decoded, because it was
+	// tampered with or signed with a key which is not in the key pairs.
+	BadCookie
+	// BadData means the stored session could not be decoded, e.g. after
+	// the key pairs or the Serializer were changed.
+	BadData
+	// UnknownKey means the stored session was encrypted with a key which
+	// is not in the Keyring.
+	UnknownKey
+	// LoadFailed means the backend failed or timed out.
+	LoadFailed
+)
+
+var loadResultNames = []string{"loaded", "missing", "expired", "bad_cookie", "bad_data", "unknown_key", "failed"}
+
+func (r LoadResult) String() string {
+	if r < 0 || int(r) >= len(loadResultNames) {
+		return "unknown"
+	}
+	return loadResultNames[r]
+}
+
+// ReportFailure passes an error which is not returned to any caller to
+// the Observer, or logs it if there is none.
+func (s *Store) ReportFailure(op string, err error) {
+	if s.Observer == nil {
+		log.Printf("Unable to %s: %v", op, err)
+		return
+	}
+	s.Observer.Failed(op, err)
+}
+
+func (s *Store) observeLoad(name string, result LoadResult, start time.Time) {
+	if s.Observer != nil {
+		s.Observer.Loaded(name, result, time.Since(start))
+	}
+}
+
+func (s *Store) observeSave(name string, start time.Time, err error) {
+	if s.Observer != nil {
+		s.Observer.Saved(name, time.Since(start), err)
+	}
+}
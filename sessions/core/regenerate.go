@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"github.com/gorilla/sessions"
+	"net/http"
+	"time"
+)
+
+// Regenerate moves session to a new ID and sends it. Call it when
+// the privileges of a session change, at least at login, so that an ID
+// planted by an attacker before is of no use (session fixation).
+//
+// The values of the session are saved under the new ID. The old ID is
+// deleted, or kept for RegenerateGrace. A session which was not saved yet
+// is simply saved, it gets a new ID anyway.
+func (s *Store) Regenerate(r *http.Request, w http.ResponseWriter, session *sessions.Session) (err error) {
+	if session.ID == "" {
+		return s.Save(r, w, session)
+	}
+	start := time.Now()
+	defer func() { s.observeSave(session.Name(), start, err) }()
+	ctx, cancel := withTimeout(r.Context(), s.Timeouts.Save)
+	defer cancel()
+
+	now := time.Now()
+	createdOn := now
+	m, ok := s.getMeta(session)
+	if ok {
+		createdOn = m.createdOn
+	}
+	var expireOld time.Time
+	if s.RegenerateGrace > 0 {
+		expireOld = now.Add(s.RegenerateGrace)
+		if ok && m.expiresOn.Before(expireOld) {
+			expireOld = m.expiresOn
+		}
+	}
+	encoded, err := s.encode(session)
+	if err != nil {
+		return err
+	}
+	rec := &Record{
+		Data:       encoded,
+		UserID:     s.userID(session),
+		CreatedOn:  createdOn,
+		ModifiedOn: now,
+		ExpiresOn:  s.Expiration.expiresOn(createdOn, now, session.Options.MaxAge),
+		Version:    1,
+	}
+	for retry := 0; ; retry++ {
+		if rec.ID, err = newSessionID(); err != nil {
+			return err
+		}
+		err = s.move(ctx, session.ID, rec, expireOld)
+		if err == ErrDuplicateID && retry < 3 {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		break
+	}
+	session.ID = rec.ID
+	s.setMeta(session, rec)
+	s.limitUserSessions(ctx, rec)
+	return s.sendID(w, session)
+}
+
+// move replaces the record of oldID by rec, see Mover.
+func (s *Store) move(ctx context.Context, oldID string, rec *Record, expireOld time.Time) error {
+	if mover, ok := s.Backend.(Mover); ok {
+		return mover.Move(ctx, oldID, rec, expireOld)
+	}
+	// Insert first, so that the session survives if the rest fails.
+	if err := s.Backend.Insert(ctx, rec); err != nil {
+		return err
+	}
+	if expireOld.IsZero() {
+		return s.Backend.Delete(ctx, oldID)
+	}
+	old, err := s.Backend.Load(ctx, oldID)
+	if err == ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	expiring := *old
+	expiring.ExpiresOn = expireOld
+	expiring.Version = Superseded
+	if err = s.Backend.Save(ctx, &expiring); err != ErrNotFound {
+		return err
+	}
+	return nil
+}
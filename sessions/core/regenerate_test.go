@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRegenerate(t *testing.T) {
+	store, backend := newTestStore(3600)
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	session, _ := store.New(req, "session-key")
+	session.Values["foo"] = "bar"
+	if err := store.Save(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	oldID := session.ID
+
+	rsp := httptest.NewRecorder()
+	if err := store.Regenerate(req, rsp, session); err != nil {
+		t.Fatalf("Error regenerating session: %v", err)
+	}
+	if session.ID == oldID {
+		t.Fatalf("Expected a new ID")
+	}
+	if backend.len() != 1 {
+		t.Errorf("Expected the old ID to be deleted; Got %d sessions", backend.len())
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Add("Cookie", rsp.Header()["Set-Cookie"][0])
+	session, err := store.New(req, "session-key")
+	if err != nil {
+		t.Fatalf("Error getting session: %v", err)
+	}
+	if session.IsNew || session.Values["foo"] != "bar" {
+		t.Errorf("Expected the values under the new ID; Got %v", session.Values)
+	}
+
+	store.RegenerateGrace = time.Minute
+	oldID = session.ID
+	if err = store.Regenerate(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error regenerating session: %v", err)
+	}
+	old, err := store.Lookup(context.Background(), "session-key", oldID)
+	if err != nil {
+		t.Fatalf("Expected the old ID to be kept for the grace period; Got %v", err)
+	}
+	if left := time.Until(ExpiresOn(old)); left > time.Minute {
+		t.Errorf("Expected the old ID to expire within a minute; Got %v", left)
+	}
+}
+
+func TestRegenerateGrace(t *testing.T) {
+	store, _ := newTestStore(3600)
+	store.RegenerateGrace = time.Minute
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	session, _ := store.New(req, "session-key")
+	session.Values["foo"] = "bar"
+	if err := store.Save(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	oldID := session.ID
+	if err := store.Regenerate(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error regenerating session: %v", err)
+	}
+
+	// A request in flight with the old cookie changes the session.
+	old, err := store.Lookup(context.Background(), "session-key", oldID)
+	if err != nil {
+		t.Fatalf("Error looking up the old ID: %v", err)
+	}
+	expiresOn := ExpiresOn(old)
+	old.Values["foo"] = "baz"
+	rsp := httptest.NewRecorder()
+	if err = store.Save(req, rsp, old); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	if cookies := rsp.Header()["Set-Cookie"]; len(cookies) != 0 {
+		t.Errorf("Expected no cookie for the old ID; Got %v", cookies)
+	}
+	old, err = store.Lookup(context.Background(), "session-key", oldID)
+	if err != nil {
+		t.Fatalf("Error looking up the old ID: %v", err)
+	}
+	if old.Values["foo"] != "bar" {
+		t.Errorf("Expected the old ID not to be saved; Got %v", old.Values["foo"])
+	}
+	if !ExpiresOn(old).Equal(expiresOn) {
+		t.Errorf("Expected the old ID to expire on %v; Got %v", expiresOn, ExpiresOn(old))
+	}
+}
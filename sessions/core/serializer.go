@@ -0,0 +1,92 @@
+package core
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/sessions"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// SessionSerializer converts session values to and from the bytes stored
+// by the backend.
+type SessionSerializer interface {
+	Serialize(s *sessions.Session) ([]byte, error)
+	Deserialize(d []byte, s *sessions.Session) error
+}
+
+// GobSerializer encodes session values with encoding/gob. Custom types
+// must be registered with gob.Register.
+type GobSerializer struct{}
+
+func (GobSerializer) Serialize(s *sessions.Session) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(s.Values); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobSerializer) Deserialize(d []byte, s *sessions.Session) error {
+	return gob.NewDecoder(bytes.NewReader(d)).Decode(&s.Values)
+}
+
+// JSONSerializer encodes session values as a JSON object, so that they
+// can be read by services written in other languages. Only string keys
+// are supported, and values come back as the generic JSON types.
+type JSONSerializer struct{}
+
+func (JSONSerializer) Serialize(s *sessions.Session) ([]byte, error) {
+	m, err := stringKeys(s.Values)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(m)
+}
+
+func (JSONSerializer) Deserialize(d []byte, s *sessions.Session) error {
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(d, &m); err != nil {
+		return err
+	}
+	for k, v := range m {
+		s.Values[k] = v
+	}
+	return nil
+}
+
+// MsgpackSerializer encodes session values as a MessagePack map. Like
+// JSONSerializer, only string keys are supported.
+type MsgpackSerializer struct{}
+
+func (MsgpackSerializer) Serialize(s *sessions.Session) ([]byte, error) {
+	m, err := stringKeys(s.Values)
+	if err != nil {
+		return nil, err
+	}
+	return msgpack.Marshal(m)
+}
+
+func (MsgpackSerializer) Deserialize(d []byte, s *sessions.Session) error {
+	m := make(map[string]interface{})
+	if err := msgpack.Unmarshal(d, &m); err != nil {
+		return err
+	}
+	for k, v := range m {
+		s.Values[k] = v
+	}
+	return nil
+}
+
+func stringKeys(values map[interface{}]interface{}) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		ks, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("non-string key value, cannot serialize session: %v", k)
+		}
+		m[ks] = v
+	}
+	return m, nil
+}
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"github.com/gorilla/sessions"
+	"testing"
+)
+
+func TestSerializers(t *testing.T) {
+	serializers := []SessionSerializer{GobSerializer{}, JSONSerializer{}, MsgpackSerializer{}}
+	for _, s := range serializers {
+		src := sessions.NewSession(nil, "session-key")
+		src.Values["name"] = "foo"
+		src.AddFlash("bar")
+		data, err := s.Serialize(src)
+		if err != nil {
+			t.Fatalf("%T: Error serializing session: %v", s, err)
+		}
+
+		dst := sessions.NewSession(nil, "session-key")
+		if err = s.Deserialize(data, dst); err != nil {
+			t.Fatalf("%T: Error deserializing session: %v", s, err)
+		}
+		if dst.Values["name"] != "foo" {
+			t.Errorf("%T: Expected foo; Got %v", s, dst.Values["name"])
+		}
+		if flashes := dst.Flashes(); len(flashes) != 1 || flashes[0] != "bar" {
+			t.Errorf("%T: Expected bar; Got %v", s, flashes)
+		}
+	}
+}
+
+func TestSerializerStringKeys(t *testing.T) {
+	src := sessions.NewSession(nil, "session-key")
+	src.Values[42] = "foo"
+	if _, err := (JSONSerializer{}).Serialize(src); err == nil {
+		t.Errorf("Expected error for non-string key")
+	}
+}
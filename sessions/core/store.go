@@ -0,0 +1,479 @@
+// Package core implements the parts shared by the session stores in this
+// repository: session cookies, expiry and encoding of session values.
+// A store only has to provide a Backend which persists the records.
+package core
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"net/http"
+	"sync"
+	"time"
+	"weak"
+)
+
+// Store implements sessions.Store on top of a Backend.
+type Store struct {
+	Backend Backend
+
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+	// Transports carry the signed session ID, a cookie if empty. The ID
+	// is read from the first transport of a request which has one, and
+	// written with all of them.
+	Transports []Transport
+
+	// Serializer encodes session values for the backend.
+	// When nil, values are encoded with securecookie as before.
+	Serializer SessionSerializer
+	// Plaintext stores the output of Serializer as is, without signing
+	// it with Codecs. Use it only when the backend is trusted.
+	Plaintext bool
+	// Keyring, if set, encrypts the session data in the backend. Records
+	// stored without encryption can still be read and are encrypted when
+	// they are loaded, if the backend is a Swapper.
+	Keyring *Keyring
+	// Compression compresses the session values before they are signed
+	// and encrypted.
+	Compression Compression
+	// MaxSize limits the size in bytes of the data stored for a session,
+	// Save returns a *SizeError for larger sessions. It defaults to the
+	// 4096 bytes securecookie used to allow, zero means no limit.
+	MaxSize int
+	// Timeouts bounds the backend calls made by New, Save and Delete in
+	// addition to the context of the request.
+	Timeouts Timeouts
+	// Expiration decides when sessions expire.
+	Expiration Expiration
+	// OnConflict decides what Save does when the session was saved by
+	// another request since it was loaded. The default, LastWriteWins,
+	// overwrites the other changes.
+	OnConflict ConflictStrategy
+	// Merge combines the values of stored, the session as saved by the
+	// other request, into session. It is used by MergeOnConflict.
+	Merge func(stored, session *sessions.Session) error
+	// TouchInterval, if set, skips saving sessions whose values did not
+	// change since they were loaded, unless they were last written more
+	// than TouchInterval ago. Then only their expiry is extended. Without
+	// it, every save of such sessions extends their expiry, see
+	// SlidingExpiry.
+	TouchInterval time.Duration
+	// RegenerateGrace keeps the old ID of a regenerated session valid for
+	// this long, so that requests already in flight do not lose the
+	// session. Their changes are not saved, and the old cookie is not sent
+	// again. Zero deletes the old ID at once.
+	RegenerateGrace time.Duration
+	// Observer, if set, is notified of loads, saves and failures, see
+	// Metrics. Without it, failures which are not returned are logged.
+	Observer Observer
+
+	// UserKey names the session value which identifies the user, e.g.
+	// "user_id". When set, the backend indexes sessions by its value,
+	// which requires a UserIndexer backend.
+	UserKey string
+
+	maxSessionsPerUser int
+
+	metas sync.Map // weak.Pointer[sessions.Session] to meta
+}
+
+// Timeouts holds the per-operation timeouts of a store. A zero duration
+// means the operation is only bounded by the context of the request.
+type Timeouts struct {
+	Load   time.Duration
+	Save   time.Duration
+	Delete time.Duration
+}
+
+var errExpired = errors.New("Session expired")
+
+// defaultMaxSize is the limit of securecookie, which MaxSize replaces.
+const defaultMaxSize = 4096
+
+func init() {
+	gob.Register(time.Time{})
+}
+
+func NewStore(backend Backend, path string, maxAge int, keyPairs ...[]byte) *Store {
+	codecs := securecookie.CodecsFromPairs(keyPairs...)
+	for _, codec := range codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxLength(0)
+		}
+	}
+	return &Store{
+		Backend: backend,
+		Codecs:  codecs,
+		Options: &sessions.Options{
+			Path:   path,
+			MaxAge: maxAge,
+		},
+		MaxSize: defaultMaxSize,
+	}
+}
+
+func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+func (s *Store) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := s.newSession(name)
+	var err error
+	if value, ok := s.readID(r, name); ok {
+		err = securecookie.DecodeMulti(name, value, &session.ID, s.Codecs...)
+		if err != nil {
+			s.observeLoad(name, BadCookie, time.Now())
+		} else {
+			ctx, cancel := withTimeout(r.Context(), s.Timeouts.Load)
+			defer cancel()
+			err = s.load(ctx, session)
+			if err == nil {
+				session.IsNew = false
+			} else if ctx.Err() != nil {
+				// Do not hand out a new session only because the backend
+				// was too slow, the caller would log the user out.
+				err = ctx.Err()
+			} else {
+				err = nil
+			}
+		}
+	}
+	return session, err
+}
+
+func (s *Store) newSession(name string) *sessions.Session {
+	session := sessions.NewSession(s, name)
+	session.Options = &sessions.Options{
+		Path:     s.Options.Path,
+		MaxAge:   s.Options.MaxAge,
+		Domain:   s.Options.Domain,
+		Secure:   s.Options.Secure,
+		HttpOnly: s.Options.HttpOnly,
+	}
+	session.IsNew = true
+	return session
+}
+
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) (err error) {
+	start := time.Now()
+	defer func() { s.observeSave(session.Name(), start, err) }()
+	ctx, cancel := withTimeout(r.Context(), s.Timeouts.Save)
+	defer cancel()
+	if session.ID == "" {
+		if err = s.insert(ctx, session); err != nil {
+			return err
+		}
+	} else if err = s.save(ctx, session); err == ErrNotFound {
+		// The session was revoked, expired or regenerated since it was
+		// loaded. Its cookie no longer loads it, or not for long; the
+		// next request gets a new one, or uses the new ID.
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return s.sendID(w, session)
+}
+
+// sendID sends the signed ID of session with the response.
+func (s *Store) sendID(w http.ResponseWriter, session *sessions.Session) error {
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	s.writeID(w, session, encoded, s.cookieOptions(session))
+	return nil
+}
+
+// cookieOptions returns the options of the session cookie. When the
+// session expires before Options.MaxAge, so does the cookie.
+func (s *Store) cookieOptions(session *sessions.Session) *sessions.Options {
+	m, ok := s.getMeta(session)
+	if !ok || session.Options.MaxAge <= 0 || s.Expiration == (Expiration{}) {
+		return session.Options
+	}
+	options := *session.Options
+	if left := int(time.Until(m.expiresOn)/time.Second) + 1; left < options.MaxAge {
+		options.MaxAge = left
+	}
+	return &options
+}
+
+func (s *Store) insert(ctx context.Context, session *sessions.Session) error {
+	now := time.Now()
+	encoded, encErr := s.encode(session)
+	if encErr != nil {
+		return encErr
+	}
+	rec := &Record{
+		Data:       encoded,
+		UserID:     s.userID(session),
+		CreatedOn:  now,
+		ModifiedOn: now,
+		ExpiresOn:  s.Expiration.expiresOn(now, now, session.Options.MaxAge),
+		Version:    1,
+	}
+	for retry := 0; ; retry++ {
+		id, idErr := newSessionID()
+		if idErr != nil {
+			return idErr
+		}
+		rec.ID = id
+		insErr := s.Backend.Insert(ctx, rec)
+		if insErr != nil {
+			// A collision of random IDs is next to impossible, but cheap to handle.
+			if insErr == ErrDuplicateID && retry < 3 {
+				continue
+			}
+			return insErr
+		}
+		session.ID = id
+		session.IsNew = false
+		s.setMeta(session, rec)
+		s.limitUserSessions(ctx, rec)
+		return nil
+	}
+}
+
+func (s *Store) Delete(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+
+	// Expire the cookie, or clear the header carrying the ID.
+	options := *session.Options
+	options.MaxAge = -1
+	s.writeID(w, session, "", &options)
+	// Clear session values.
+	for k := range session.Values {
+		delete(session.Values, k)
+	}
+	s.metas.Delete(weak.Make(session))
+
+	ctx, cancel := withTimeout(r.Context(), s.Timeouts.Delete)
+	defer cancel()
+	return s.Backend.Delete(ctx, session.ID)
+}
+
+// save writes a session which was loaded from the backend. It returns
+// ErrNotFound if the record was deleted since, or was superseded by
+// Regenerate.
+func (s *Store) save(ctx context.Context, session *sessions.Session) error {
+	if session.IsNew == true {
+		return s.insert(ctx, session)
+	}
+	m, _ := s.getMeta(session)
+	if m.version == Superseded {
+		return ErrNotFound
+	}
+	if s.SlidingExpiry() && session.Options.MaxAge >= 0 && s.unchanged(session, m) {
+		if time.Since(m.modifiedOn) < s.TouchInterval {
+			return nil
+		}
+		return s.touch(ctx, session, m)
+	}
+	rec, err := s.newRecord(session, m)
+	if err != nil {
+		return err
+	}
+	if s.OnConflict == LastWriteWins {
+		err = s.Backend.Save(ctx, rec)
+	} else {
+		rec, err = s.swap(ctx, session, rec, m.version)
+	}
+	if err != nil {
+		return err
+	}
+	s.setMeta(session, rec)
+	if rec.UserID != m.userID {
+		s.limitUserSessions(ctx, rec)
+	}
+	return nil
+}
+
+// newRecord encodes session for the record loaded with m.
+func (s *Store) newRecord(session *sessions.Session, m meta) (*Record, error) {
+	now := time.Now()
+	createdOn := m.createdOn
+	if createdOn.IsZero() {
+		createdOn = now
+	}
+	encoded, err := s.encode(session)
+	if err != nil {
+		return nil, err
+	}
+	return &Record{
+		ID:         session.ID,
+		Data:       encoded,
+		UserID:     s.userID(session),
+		CreatedOn:  createdOn,
+		ModifiedOn: now,
+		ExpiresOn:  s.Expiration.expiresOn(createdOn, now, session.Options.MaxAge),
+		Version:    m.version + 1,
+	}, nil
+}
+
+func (s *Store) load(ctx context.Context, session *sessions.Session) error {
+	start := time.Now()
+	result := LoadFailed
+	defer func() { s.observeLoad(session.Name(), result, start) }()
+
+	rec, err := s.Backend.Load(ctx, session.ID)
+	if err == ErrNotFound {
+		result = Missing
+	}
+	if err != nil {
+		return err
+	}
+	if s.Expiration.expired(rec, time.Now()) {
+		result = Expired
+		return errExpired
+	}
+	if err = s.decodeRecord(rec, session); err != nil {
+		result = BadData
+		if err == ErrUnknownKey {
+			result = UnknownKey
+		}
+		return err
+	}
+	result = Loaded
+	if s.Keyring != nil && rec.Version != Superseded && s.Keyring.stale(rec.Data) {
+		s.reencrypt(ctx, session, rec)
+	}
+	return nil
+}
+
+// reencrypt stores rec, which session was loaded from, encrypted with the
+// primary key of the keyring. It gives way to any other change of the
+// record, which is encrypted then, and only works with a Swapper. Failures
+// are only logged, the record is tried again on the next load.
+func (s *Store) reencrypt(ctx context.Context, session *sessions.Session, rec *Record) {
+	swapper, ok := s.Backend.(Swapper)
+	if !ok {
+		return
+	}
+	data := rec.Data
+	if isEncrypted(data) {
+		var err error
+		if data, err = s.Keyring.Decrypt(data); err != nil {
+			s.ReportFailure("re-encrypt session", err)
+			return
+		}
+	}
+	encrypted, err := s.Keyring.Encrypt(data)
+	if err != nil {
+		s.ReportFailure("re-encrypt session", err)
+		return
+	}
+	updated := *rec
+	updated.Data = encrypted
+	updated.Version = rec.Version + 1
+	err = swapper.Swap(ctx, &updated, rec.Version)
+	if err == ErrVersionConflict || err == ErrNotFound {
+		return
+	} else if err != nil {
+		s.ReportFailure("re-encrypt session", err)
+		return
+	}
+	s.setMeta(session, &updated)
+}
+
+// decodeRecord sets the values of session from rec.
+func (s *Store) decodeRecord(rec *Record, session *sessions.Session) error {
+	err := s.decode(rec.Data, session)
+	if err != nil {
+		return err
+	}
+	s.setMeta(session, rec)
+	return nil
+}
+
+func (s *Store) encode(session *sessions.Session) ([]byte, error) {
+	data, err := s.marshal(session)
+	if err == nil && s.Keyring != nil {
+		data, err = s.Keyring.Encrypt(data)
+	}
+	if err == nil && s.MaxSize > 0 && len(data) > s.MaxSize {
+		return nil, &SizeError{Name: session.Name(), Size: len(data), MaxSize: s.MaxSize}
+	}
+	return data, err
+}
+
+func (s *Store) decode(data []byte, session *sessions.Session) error {
+	if isEncrypted(data) {
+		if s.Keyring == nil {
+			return ErrUnknownKey
+		}
+		var err error
+		if data, err = s.Keyring.Decrypt(data); err != nil {
+			return err
+		}
+	}
+	return s.unmarshal(data, session)
+}
+
+// marshal encodes the values of session, compressed if Compression is set
+// and signed unless Plaintext is set.
+func (s *Store) marshal(session *sessions.Session) ([]byte, error) {
+	if s.Serializer == nil && !s.Plaintext && s.Compression == NoCompression {
+		encoded, err := securecookie.EncodeMulti(session.Name(), session.Values, s.Codecs...)
+		return []byte(encoded), err
+	}
+	data, err := s.serializer().Serialize(session)
+	if err != nil {
+		return nil, err
+	}
+	var magic []byte
+	if s.Compression != NoCompression {
+		if data, err = compress(s.Compression, data); err != nil {
+			return nil, err
+		}
+		// The marker stays outside of the signature, so that unmarshal
+		// knows how to read the record.
+		magic = compressedMagic[s.Compression]
+	}
+	if !s.Plaintext {
+		encoded, err := securecookie.EncodeMulti(session.Name(), data, s.Codecs...)
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(encoded)
+	}
+	if magic == nil {
+		return data, nil
+	}
+	return append(append(make([]byte, 0, len(magic)+len(data)), magic...), data...), nil
+}
+
+func (s *Store) unmarshal(encoded []byte, session *sessions.Session) error {
+	c, encoded, compressed := splitCompressed(encoded)
+	if s.Serializer == nil && !s.Plaintext && !compressed {
+		return securecookie.DecodeMulti(session.Name(), string(encoded), &session.Values, s.Codecs...)
+	}
+	data := encoded
+	if !s.Plaintext {
+		if err := securecookie.DecodeMulti(session.Name(), string(encoded), &data, s.Codecs...); err != nil {
+			return err
+		}
+	}
+	if compressed {
+		var err error
+		if data, err = decompress(c, data); err != nil {
+			return err
+		}
+	}
+	return s.serializer().Deserialize(data, session)
+}
+
+func (s *Store) serializer() SessionSerializer {
+	if s.Serializer == nil {
+		return GobSerializer{}
+	}
+	return s.Serializer
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"context"
+	"github.com/gorilla/sessions"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBackend keeps records in a map which the tests can inspect and
+// change.
+type fakeBackend struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+func newTestStore(maxAge int) (*Store, *fakeBackend) {
+	b := &fakeBackend{records: make(map[string]Record)}
+	return NewStore(b, "/", maxAge, []byte("secret-key")), b
+}
+
+func (b *fakeBackend) get(id string) Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.records[id]
+}
+
+func (b *fakeBackend) set(rec Record) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.records[rec.ID] = rec
+}
+
+func (b *fakeBackend) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.records)
+}
+
+func (b *fakeBackend) Load(ctx context.Context, id string) (*Record, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rec, ok := b.records[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &rec, nil
+}
+
+func (b *fakeBackend) Insert(ctx context.Context, rec *Record) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.records[rec.ID]; ok {
+		return ErrDuplicateID
+	}
+	b.records[rec.ID] = *rec
+	return nil
+}
+
+func (b *fakeBackend) Save(ctx context.Context, rec *Record) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.records[rec.ID]; !ok {
+		return ErrNotFound
+	}
+	b.records[rec.ID] = *rec
+	return nil
+}
+
+func (b *fakeBackend) Delete(ctx context.Context, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.records, id)
+	return nil
+}
+
+func (b *fakeBackend) Swap(ctx context.Context, rec *Record, version int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stored, ok := b.records[rec.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if stored.Version != version {
+		return ErrVersionConflict
+	}
+	b.records[rec.ID] = *rec
+	return nil
+}
+
+func (b *fakeBackend) Touch(ctx context.Context, id string, modifiedOn, expiresOn time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rec, ok := b.records[id]
+	if !ok {
+		return ErrNotFound
+	}
+	rec.ModifiedOn, rec.ExpiresOn = modifiedOn, expiresOn
+	b.records[id] = rec
+	return nil
+}
+
+func (b *fakeBackend) Count(ctx context.Context) (int64, error) {
+	return int64(b.len()), nil
+}
+
+func (b *fakeBackend) List(ctx context.Context, cursor string, limit int) ([]*Record, string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ids := make([]string, 0, len(b.records))
+	for id := range b.records {
+		if id > cursor {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	next := ""
+	if len(ids) > limit {
+		ids, next = ids[:limit], ids[limit-1]
+	}
+	list := make([]*Record, 0, len(ids))
+	for _, id := range ids {
+		rec := b.records[id]
+		list = append(list, &rec)
+	}
+	return list, next, nil
+}
+
+func TestStoreValues(t *testing.T) {
+	store, backend := newTestStore(3600)
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	rsp := httptest.NewRecorder()
+	session, _ := store.New(req, "session-key")
+	session.Values["foo"] = "bar"
+	if err := store.Save(req, rsp, session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Add("Cookie", rsp.Header()["Set-Cookie"][0])
+	session, err := store.New(req, "session-key")
+	if err != nil || session.IsNew {
+		t.Fatalf("Error getting session: %v", err)
+	}
+	// Only the values of the application are in Values.
+	if len(session.Values) != 1 || session.Values["foo"] != "bar" {
+		t.Errorf("Expected foo=bar only; Got %v", session.Values)
+	}
+	if CreatedOn(session).IsZero() || ExpiresOn(session).IsZero() {
+		t.Errorf("Expected the record times")
+	}
+
+	// Clearing the values keeps the session an update of its record.
+	for k := range session.Values {
+		delete(session.Values, k)
+	}
+	if err = store.Save(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	if rec := backend.get(session.ID); rec.Version != 2 {
+		t.Errorf("Expected version 2; Got %d", rec.Version)
+	}
+	if !CreatedOn(sessions.NewSession(store, "session-key")).IsZero() {
+		t.Errorf("Expected no creation time for a session which was not saved")
+	}
+}
+
+func TestSetMaxSessionsPerUser(t *testing.T) {
+	store, _ := newTestStore(3600)
+	if err := store.SetMaxSessionsPerUser(3); err != ErrNotSupported {
+		t.Errorf("Expected ErrNotSupported without a UserIndexer; Got %v", err)
+	}
+	if err := store.SetMaxSessionsPerUser(0); err != nil {
+		t.Errorf("Error removing the limit: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"context"
+	"github.com/gorilla/sessions"
+	"reflect"
+	"time"
+)
+
+// SlidingExpiry reports whether saving a session whose values did not
+// change extends its expiry: with TouchInterval, with an idle timeout, or
+// when sessions are kept for Options.MaxAge after they were last saved,
+// the default. The middleware then saves sessions which were only read.
+func (s *Store) SlidingExpiry() bool {
+	return s.TouchInterval > 0 || s.Expiration.IdleTimeout > 0 || s.Options.MaxAge > 0
+}
+
+// unchanged reports whether the values of session are those stored in
+// the record it was loaded from. The stored data is decoded again rather
+// than hashed, because gob encodes maps in random order.
+func (s *Store) unchanged(session *sessions.Session, m meta) bool {
+	if m.data == nil {
+		return false
+	}
+	stored := s.newSession(session.Name())
+	if err := s.decode(m.data, stored); err != nil {
+		return false
+	}
+	if len(stored.Values) != len(session.Values) {
+		return false
+	}
+	for k, v := range stored.Values {
+		if cur, ok := session.Values[k]; !ok || !reflect.DeepEqual(v, cur) {
+			return false
+		}
+	}
+	return true
+}
+
+// touch extends the expiry of an unchanged session without writing its
+// values, if the backend supports it. Like save, it returns ErrNotFound if
+// the record was deleted.
+func (s *Store) touch(ctx context.Context, session *sessions.Session, m meta) error {
+	now := time.Now()
+	rec := &Record{
+		ID:         session.ID,
+		Data:       m.data,
+		UserID:     s.userID(session),
+		CreatedOn:  m.createdOn,
+		ModifiedOn: now,
+		ExpiresOn:  s.Expiration.expiresOn(m.createdOn, now, session.Options.MaxAge),
+		Version:    m.version,
+	}
+	var err error
+	if toucher, ok := s.Backend.(Toucher); ok {
+		err = toucher.Touch(ctx, rec.ID, rec.ModifiedOn, rec.ExpiresOn)
+	} else {
+		err = s.Backend.Save(ctx, rec)
+	}
+	if err != nil {
+		return err
+	}
+	s.setMeta(session, rec)
+	return nil
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"github.com/gorilla/sessions"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTouch(t *testing.T) {
+	store, backend := newTestStore(3600)
+	store.TouchInterval = time.Minute
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	session, _ := store.New(req, "session-key")
+	session.Values["foo"] = "bar"
+	rsp := httptest.NewRecorder()
+	if err := store.Save(req, rsp, session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	saved := backend.get(session.ID)
+
+	load := func() *sessions.Session {
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		req.Header.Add("Cookie", rsp.Header()["Set-Cookie"][0])
+		session, err := store.New(req, "session-key")
+		if err != nil || session.IsNew {
+			t.Fatalf("Error getting session: %v", err)
+		}
+		return session
+	}
+
+	// Unchanged and recently written: nothing is written.
+	session = load()
+	if err := store.Save(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	if rec := backend.get(session.ID); !rec.ModifiedOn.Equal(saved.ModifiedOn) || rec.Version != saved.Version {
+		t.Errorf("Expected the unchanged session not to be written")
+	}
+
+	// Unchanged but written long ago: only the expiry is extended.
+	saved.ModifiedOn = saved.ModifiedOn.Add(-2 * time.Minute)
+	backend.set(saved)
+	session = load()
+	if err := store.Save(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	rec := backend.get(session.ID)
+	if !rec.ModifiedOn.After(saved.ModifiedOn) || !bytes.Equal(rec.Data, saved.Data) {
+		t.Errorf("Expected only the times to be updated")
+	}
+
+	// Changed: the values are written.
+	session = load()
+	session.Values["bar"] = 1
+	if err := store.Save(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	if rec = backend.get(session.ID); rec.Version != saved.Version+1 {
+		t.Errorf("Expected the changed session to be written")
+	}
+
+	// Without TouchInterval, an idle timeout extends the expiry every time.
+	store.TouchInterval = 0
+	store.Expiration = Expiration{IdleTimeout: 30 * time.Minute}
+	rec = backend.get(session.ID)
+	session = load()
+	if err := store.Save(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	touched := backend.get(session.ID)
+	if left := time.Until(touched.ExpiresOn); left < 29*time.Minute || left > 30*time.Minute || touched.Version != rec.Version {
+		t.Errorf("Expected only the expiry to be set to 30 minutes; Got %v, version %d", left, touched.Version)
+	}
+
+	// Revoked while unchanged: touching it does not store it again.
+	rec.ModifiedOn = rec.ModifiedOn.Add(-2 * time.Minute)
+	backend.set(rec)
+	session = load()
+	if err := store.Revoke(context.Background(), session.ID); err != nil {
+		t.Fatalf("Error revoking session: %v", err)
+	}
+	if err := store.Save(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	if backend.len() != 0 {
+		t.Errorf("Expected the revoked session to stay deleted")
+	}
+}
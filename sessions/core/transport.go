@@ -0,0 +1,99 @@
+package core
+
+import (
+	"github.com/gorilla/sessions"
+	"net/http"
+	"strings"
+)
+
+// Transport carries the signed session ID between the client and the
+// store, in a cookie by default. Clients which do not keep cookies, e.g.
+// mobile apps, can send it in a header instead.
+type Transport interface {
+	// Read returns the signed ID of the session named name sent with r,
+	// or "" if there is none.
+	Read(r *http.Request, name string) string
+	// Write sends the signed ID of the session named name with the
+	// response. value is "" and options.MaxAge < 0 when the session is
+	// deleted.
+	Write(w http.ResponseWriter, name, value string, options *sessions.Options)
+}
+
+// CookieTransport sends the ID in a cookie named like the session.
+type CookieTransport struct{}
+
+func (CookieTransport) Read(r *http.Request, name string) string {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+func (CookieTransport) Write(w http.ResponseWriter, name, value string, options *sessions.Options) {
+	http.SetCookie(w, sessions.NewCookie(name, value, options))
+}
+
+// HeaderTransport reads the ID from the request header Header and sends
+// it in the response header of the same name, which is empty when the
+// session is deleted. The client sends back what it got last.
+type HeaderTransport struct {
+	Header string
+}
+
+func (t HeaderTransport) Read(r *http.Request, name string) string {
+	return r.Header.Get(t.Header)
+}
+
+func (t HeaderTransport) Write(w http.ResponseWriter, name, value string, options *sessions.Options) {
+	w.Header().Set(t.Header, value)
+}
+
+// BearerTransport reads the ID from an "Authorization: Bearer" request
+// header. It is sent in the response header Header, "X-Session-Token" if
+// empty.
+type BearerTransport struct {
+	Header string
+}
+
+func (t BearerTransport) Read(r *http.Request, name string) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(auth[7:])
+}
+
+func (t BearerTransport) Write(w http.ResponseWriter, name, value string, options *sessions.Options) {
+	header := t.Header
+	if header == "" {
+		header = "X-Session-Token"
+	}
+	w.Header().Set(header, value)
+}
+
+// transports returns Transports, or a CookieTransport if it is empty.
+func (s *Store) transports() []Transport {
+	if len(s.Transports) == 0 {
+		return []Transport{CookieTransport{}}
+	}
+	return s.Transports
+}
+
+// readID returns the signed ID of the session named name sent with r,
+// taken from the first transport which has one.
+func (s *Store) readID(r *http.Request, name string) (string, bool) {
+	for _, t := range s.transports() {
+		if value := t.Read(r, name); value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// writeID sends the signed ID of session with every transport.
+func (s *Store) writeID(w http.ResponseWriter, session *sessions.Session, value string, options *sessions.Options) {
+	for _, t := range s.transports() {
+		t.Write(w, session.Name(), value, options)
+	}
+}
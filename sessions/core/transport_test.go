@@ -0,0 +1,54 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransports(t *testing.T) {
+	store, _ := newTestStore(3600)
+	store.Transports = []Transport{BearerTransport{}, HeaderTransport{Header: "X-Session"}}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	rsp := httptest.NewRecorder()
+	session, _ := store.New(req, "session-key")
+	session.Values["foo"] = "bar"
+	if err := store.Save(req, rsp, session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	token := rsp.Header().Get("X-Session-Token")
+	if token == "" || rsp.Header().Get("X-Session") != token || rsp.Header().Get("Set-Cookie") != "" {
+		t.Fatalf("Expected the ID in the headers only; Got %v", rsp.Header())
+	}
+
+	for _, header := range []http.Header{
+		{"Authorization": {"Bearer " + token}},
+		{"X-Session": {token}},
+	} {
+		req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+		for k, v := range header {
+			req.Header.Set(k, v[0])
+		}
+		session, err := store.New(req, "session-key")
+		if err != nil || session.IsNew || session.Values["foo"] != "bar" {
+			t.Errorf("%v: Expected the session to load; Got %v, %v", header, session.Values, err)
+		}
+	}
+
+	// The ID is signed like in a cookie.
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Set("Authorization", "Bearer "+token[:len(token)-2])
+	if session, _ = store.New(req, "session-key"); !session.IsNew {
+		t.Errorf("Expected a new session for a tampered token")
+	}
+
+	rsp = httptest.NewRecorder()
+	session.ID = ""
+	if err := store.Delete(req, rsp, session); err != nil {
+		t.Fatalf("Error deleting session: %v", err)
+	}
+	if v, ok := rsp.Header()["X-Session-Token"]; !ok || v[0] != "" {
+		t.Errorf("Expected an empty token; Got %v", rsp.Header())
+	}
+}
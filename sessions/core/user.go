@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/gorilla/sessions"
+	"time"
+)
+
+// UserSessions returns the active sessions named name of userID, oldest
+// first.
+func (s *Store) UserSessions(ctx context.Context, name, userID string) ([]*sessions.Session, error) {
+	indexer, ok := s.Backend.(UserIndexer)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	recs, err := indexer.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*sessions.Session, 0, len(recs))
+	for _, rec := range recs {
+		if s.Expiration.expired(rec, time.Now()) {
+			continue
+		}
+		session := s.newSession(name)
+		session.ID = rec.ID
+		if err := s.decodeRecord(rec, session); err != nil {
+			continue
+		}
+		session.IsNew = false
+		list = append(list, session)
+	}
+	return list, nil
+}
+
+// RevokeUser deletes all sessions of userID, e.g. to log a user out
+// everywhere after a password reset, and returns their number.
+func (s *Store) RevokeUser(ctx context.Context, userID string) (int64, error) {
+	indexer, ok := s.Backend.(UserIndexer)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	return indexer.DeleteByUser(ctx, userID)
+}
+
+// userID returns the value of UserKey in session as a string, or "".
+func (s *Store) userID(session *sessions.Session) string {
+	if s.UserKey == "" {
+		return ""
+	}
+	v, ok := session.Values[s.UserKey]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// SetMaxSessionsPerUser limits the number of sessions of a user. When a
+// session is saved for a user, e.g. at login, the oldest sessions of the
+// user are deleted until at most n are left. Zero means no limit. It
+// requires UserKey and returns ErrNotSupported if the backend is not a
+// UserIndexer.
+func (s *Store) SetMaxSessionsPerUser(n int) error {
+	if _, ok := s.Backend.(UserIndexer); !ok && n > 0 {
+		return ErrNotSupported
+	}
+	s.maxSessionsPerUser = n
+	return nil
+}
+
+// limitUserSessions deletes the oldest sessions of the user of rec, which
+// was just stored for that user, see SetMaxSessionsPerUser. rec itself is
+// never deleted. Failures are only reported: rec is stored either way.
+func (s *Store) limitUserSessions(ctx context.Context, rec *Record) {
+	if rec.UserID == "" || s.maxSessionsPerUser <= 0 {
+		return
+	}
+	indexer, ok := s.Backend.(UserIndexer)
+	if !ok {
+		s.ReportFailure("limit user sessions", ErrNotSupported)
+		return
+	}
+	recs, err := indexer.ListIDsByUser(ctx, rec.UserID)
+	if err != nil {
+		s.ReportFailure("limit user sessions", err)
+		return
+	}
+	var others []*Record
+	for _, r := range recs {
+		if r.ID != rec.ID {
+			others = append(others, r)
+		}
+	}
+	for i := 0; i < len(others)-(s.maxSessionsPerUser-1); i++ {
+		if err = s.Backend.Delete(ctx, others[i].ID); err != nil {
+			s.ReportFailure("limit user sessions", err)
+			return
+		}
+	}
+}
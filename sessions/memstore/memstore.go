@@ -0,0 +1,237 @@
+// Package memstore is a Gorilla Sessions backend keeping sessions in the
+// memory of the process. Sessions are lost on restart and not shared
+// between instances, so it is meant for development and tests.
+package memstore
+
+import (
+	"context"
+	"github.com/kimiazhu/golib/sessions/core"
+	"sort"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often expired sessions are removed on insert.
+const sweepInterval = time.Minute
+
+type MemStore struct {
+	*core.Store
+	backend *memBackend
+}
+
+type memBackend struct {
+	mu        sync.RWMutex
+	records   map[string]core.Record
+	lastSweep time.Time
+}
+
+func NewMemStore(path string, maxAge int, keyPairs ...[]byte) *MemStore {
+	backend := &memBackend{
+		records:   make(map[string]core.Record),
+		lastSweep: time.Now(),
+	}
+	return &MemStore{
+		Store:   core.NewStore(backend, path, maxAge, keyPairs...),
+		backend: backend,
+	}
+}
+
+// Len returns the number of stored sessions, including expired ones that
+// were not removed yet.
+func (m *MemStore) Len() int {
+	m.backend.mu.RLock()
+	defer m.backend.mu.RUnlock()
+	return len(m.backend.records)
+}
+
+// DeleteExpired removes all expired sessions and returns their number.
+func (m *MemStore) DeleteExpired(ctx context.Context) (int64, error) {
+	m.backend.mu.Lock()
+	defer m.backend.mu.Unlock()
+	return m.backend.sweep(), nil
+}
+
+func (b *memBackend) Load(ctx context.Context, id string) (*core.Record, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	rec, ok := b.records[id]
+	if !ok {
+		return nil, core.ErrNotFound
+	}
+	return &rec, nil
+}
+
+func (b *memBackend) Insert(ctx context.Context, rec *core.Record) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.records[rec.ID]; ok {
+		return core.ErrDuplicateID
+	}
+	b.records[rec.ID] = *rec
+	if time.Since(b.lastSweep) > sweepInterval {
+		b.sweep()
+	}
+	return nil
+}
+
+func (b *memBackend) Save(ctx context.Context, rec *core.Record) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.records[rec.ID]; !ok {
+		return core.ErrNotFound
+	}
+	b.records[rec.ID] = *rec
+	return nil
+}
+
+func (b *memBackend) Swap(ctx context.Context, rec *core.Record, version int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	old, ok := b.records[rec.ID]
+	if !ok {
+		return core.ErrNotFound
+	}
+	if old.Version != version {
+		return core.ErrVersionConflict
+	}
+	b.records[rec.ID] = *rec
+	return nil
+}
+
+func (b *memBackend) Touch(ctx context.Context, id string, modifiedOn, expiresOn time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rec, ok := b.records[id]
+	if !ok {
+		return core.ErrNotFound
+	}
+	rec.ModifiedOn, rec.ExpiresOn = modifiedOn, expiresOn
+	b.records[id] = rec
+	return nil
+}
+
+func (b *memBackend) Move(ctx context.Context, oldID string, rec *core.Record, expireOld time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.records[rec.ID]; ok {
+		return core.ErrDuplicateID
+	}
+	b.records[rec.ID] = *rec
+	if old, ok := b.records[oldID]; ok && !expireOld.IsZero() {
+		old.ExpiresOn = expireOld
+		old.Version = core.Superseded
+		b.records[oldID] = old
+	} else {
+		delete(b.records, oldID)
+	}
+	return nil
+}
+
+func (b *memBackend) Delete(ctx context.Context, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.records, id)
+	return nil
+}
+
+func (b *memBackend) Count(ctx context.Context) (int64, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var n int64
+	now := time.Now()
+	for _, rec := range b.records {
+		if !rec.ExpiresOn.Before(now) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// List pages through the records ordered by ID, the cursor is the last ID
+// of the previous page.
+func (b *memBackend) List(ctx context.Context, cursor string, limit int) ([]*core.Record, string, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	ids := make([]string, 0, len(b.records))
+	for id := range b.records {
+		if id > cursor {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	next := ""
+	if len(ids) > limit {
+		ids = ids[:limit]
+		next = ids[limit-1]
+	}
+	recs := make([]*core.Record, len(ids))
+	for i, id := range ids {
+		rec := b.records[id]
+		recs[i] = &rec
+	}
+	return recs, next, nil
+}
+
+// ListByUser returns the records of userID ordered by creation time.
+func (b *memBackend) ListByUser(ctx context.Context, userID string) ([]*core.Record, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var recs []*core.Record
+	for _, rec := range b.records {
+		if rec.UserID == userID {
+			rec := rec
+			recs = append(recs, &rec)
+		}
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].CreatedOn.Equal(recs[j].CreatedOn) {
+			return recs[i].ID < recs[j].ID
+		}
+		return recs[i].CreatedOn.Before(recs[j].CreatedOn)
+	})
+	return recs, nil
+}
+
+// ListIDsByUser returns the unexpired records of userID ordered by
+// creation time.
+func (b *memBackend) ListIDsByUser(ctx context.Context, userID string) ([]*core.Record, error) {
+	recs, err := b.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	active := recs[:0]
+	for _, rec := range recs {
+		if rec.ExpiresOn.After(now) {
+			active = append(active, rec)
+		}
+	}
+	return active, nil
+}
+
+func (b *memBackend) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var n int64
+	for id, rec := range b.records {
+		if rec.UserID == userID {
+			delete(b.records, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// sweep removes expired records, b.mu must be held.
+func (b *memBackend) sweep() int64 {
+	var n int64
+	now := time.Now()
+	for id, rec := range b.records {
+		if rec.ExpiresOn.Before(now) {
+			delete(b.records, id)
+			n++
+		}
+	}
+	b.lastSweep = now
+	return n
+}
@@ -0,0 +1,221 @@
+package memstore
+
+import (
+	"context"
+	"github.com/gorilla/sessions"
+	"github.com/kimiazhu/golib/sessions/core"
+	"github.com/kimiazhu/golib/sessions/storetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemStore(t *testing.T) {
+	store := NewMemStore("/", 3600, []byte("secret-key"))
+
+	// Round 1: a new session is saved.
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	rsp := httptest.NewRecorder()
+	session, err := store.Get(req, "session-key")
+	if err != nil {
+		t.Fatalf("Error getting session: %v", err)
+	}
+	if !session.IsNew {
+		t.Errorf("Expected a new session")
+	}
+	session.Values["foo"] = "bar"
+	if err = sessions.Save(req, rsp); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	cookies := rsp.Header()["Set-Cookie"]
+	if len(cookies) != 1 {
+		t.Fatalf("No cookies. Header: %v", rsp.Header())
+	}
+	if store.Len() != 1 {
+		t.Errorf("Expected 1 session; Got %d", store.Len())
+	}
+
+	// Round 2: the session is loaded and deleted.
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Add("Cookie", cookies[0])
+	rsp = httptest.NewRecorder()
+	if session, err = store.Get(req, "session-key"); err != nil {
+		t.Fatalf("Error getting session: %v", err)
+	}
+	if session.IsNew || session.Values["foo"] != "bar" {
+		t.Fatalf("Expected foo=bar; Got %v", session.Values)
+	}
+	if err = store.Delete(req, rsp, session); err != nil {
+		t.Fatalf("Error deleting session: %v", err)
+	}
+	if store.Len() != 0 {
+		t.Errorf("Expected no session; Got %d", store.Len())
+	}
+}
+
+func TestMemStoreAdmin(t *testing.T) {
+	store := NewMemStore("/", 3600, []byte("secret-key"))
+	ctx := context.Background()
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		session, _ := store.New(req, "session-key")
+		session.Values["user_id"] = i % 2
+		if err := store.Save(req, httptest.NewRecorder(), session); err != nil {
+			t.Fatalf("Error saving session: %v", err)
+		}
+		ids = append(ids, session.ID)
+	}
+
+	if n, err := store.Count(ctx); err != nil || n != 5 {
+		t.Errorf("Expected 5 sessions; Got %d, %v", n, err)
+	}
+	session, err := store.Lookup(ctx, "session-key", ids[1])
+	if err != nil {
+		t.Fatalf("Error looking up session: %v", err)
+	}
+	if session.Values["user_id"] != 1 {
+		t.Errorf("Expected user_id 1; Got %v", session.Values["user_id"])
+	}
+
+	var seen int
+	for cursor := ""; ; {
+		list, next, err := store.List(ctx, "session-key", cursor, 2)
+		if err != nil {
+			t.Fatalf("Error listing sessions: %v", err)
+		}
+		seen += len(list)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if seen != 5 {
+		t.Errorf("Expected to list 5 sessions; Got %d", seen)
+	}
+	if _, _, err = store.List(ctx, "session-key", "", 0); err == nil {
+		t.Errorf("Expected an error for limit 0")
+	}
+
+	n, err := store.RevokeMatching(ctx, "session-key", func(s *sessions.Session) bool {
+		return s.Values["user_id"] == 0
+	})
+	if err != nil || n != 3 {
+		t.Errorf("Expected 3 revoked sessions; Got %d, %v", n, err)
+	}
+	if err = store.Revoke(ctx, ids[1]); err != nil {
+		t.Fatalf("Error revoking session: %v", err)
+	}
+	if _, err = store.Lookup(ctx, "session-key", ids[1]); err != core.ErrNotFound {
+		t.Errorf("Expected ErrNotFound; Got %v", err)
+	}
+	if store.Len() != 1 {
+		t.Errorf("Expected 1 session left; Got %d", store.Len())
+	}
+}
+
+func TestMemStoreUserSessions(t *testing.T) {
+	store := NewMemStore("/", 3600, []byte("secret-key"))
+	store.UserKey = "user_id"
+	if err := store.SetMaxSessionsPerUser(2); err != nil {
+		t.Fatalf("Error limiting user sessions: %v", err)
+	}
+	ctx := context.Background()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		session, _ := store.New(req, "session-key")
+		session.Values["user_id"] = 42
+		if err := store.Save(req, httptest.NewRecorder(), session); err != nil {
+			t.Fatalf("Error saving session: %v", err)
+		}
+		ids = append(ids, session.ID)
+	}
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	session, _ := store.New(req, "session-key")
+	if err := store.Save(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+
+	list, err := store.UserSessions(ctx, "session-key", "42")
+	if err != nil {
+		t.Fatalf("Error listing user sessions: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 sessions; Got %d", len(list))
+	}
+	if _, err = store.Lookup(ctx, "session-key", ids[0]); err != core.ErrNotFound {
+		t.Errorf("Expected the oldest session to be evicted; Got %v", err)
+	}
+
+	// Saving a session of the user again evicts nothing, logging in the
+	// anonymous session does.
+	list[0].Values["foo"] = "bar"
+	if err = store.Save(req, httptest.NewRecorder(), list[0]); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	if list, _ = store.UserSessions(ctx, "session-key", "42"); len(list) != 2 {
+		t.Errorf("Expected 2 sessions; Got %d", len(list))
+	}
+	session.Values["user_id"] = 42
+	if err = store.Save(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	if _, err = store.Lookup(ctx, "session-key", ids[1]); err != core.ErrNotFound {
+		t.Errorf("Expected the oldest session to be evicted at login; Got %v", err)
+	}
+
+	n, err := store.RevokeUser(ctx, "42")
+	if err != nil || n != 2 {
+		t.Errorf("Expected 2 revoked sessions; Got %d, %v", n, err)
+	}
+	if store.Len() != 0 {
+		t.Errorf("Expected no session left; Got %d", store.Len())
+	}
+}
+
+func TestMemStoreRegenerateGrace(t *testing.T) {
+	store := NewMemStore("/", 3600, []byte("secret-key"))
+	store.RegenerateGrace = 200 * time.Millisecond
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	session, _ := store.New(req, "session-key")
+	session.Values["foo"] = "bar"
+	if err := store.Save(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	oldID := session.ID
+	if err := store.Regenerate(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error regenerating session: %v", err)
+	}
+
+	// Saving through the old ID during the grace period does not extend it.
+	old, err := store.Lookup(context.Background(), "session-key", oldID)
+	if err != nil {
+		t.Fatalf("Error looking up the old ID: %v", err)
+	}
+	old.Values["foo"] = "baz"
+	if err = store.Save(req, httptest.NewRecorder(), old); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if _, err = store.Lookup(context.Background(), "session-key", oldID); err != core.ErrNotFound {
+		t.Errorf("Expected the old ID to expire with the grace period; Got %v", err)
+	}
+	if _, err = store.Lookup(context.Background(), "session-key", session.ID); err != nil {
+		t.Errorf("Error looking up the new ID: %v", err)
+	}
+}
+
+func TestMemStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, maxAge int) sessions.Store {
+		return NewMemStore("/", maxAge, []byte("secret-key"))
+	})
+}
+
+func TestMemStoreConflicts(t *testing.T) {
+	storetest.RunConflicts(t, NewMemStore("/", 3600, []byte("secret-key")).Store)
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+)
+
+const (
+	// CSRFKey is the session value holding the CSRF token.
+	CSRFKey = "_csrf"
+	// CSRFHeader and CSRFField carry the token of a request, e.g. set by
+	// JavaScript or in a hidden field of a form.
+	CSRFHeader = "X-CSRF-Token"
+	CSRFField  = "csrf_token"
+)
+
+// CSRFToken returns the CSRF token of the session named name, or "" if
+// the middleware does not check CSRF tokens. Put it into forms and pages
+// which send requests with an unsafe method.
+func CSRFToken(r *http.Request, name string) string {
+	session := Get(r, name)
+	if session == nil {
+		return ""
+	}
+	token, _ := session.String(CSRFKey)
+	return token
+}
+
+func (s *Session) ensureCSRFToken() error {
+	if _, ok := s.String(CSRFKey); ok {
+		return nil
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	s.Set(CSRFKey, base64.RawURLEncoding.EncodeToString(b))
+	return nil
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func validCSRFToken(r *http.Request, session *Session) bool {
+	want, _ := session.String(CSRFKey)
+	got := r.Header.Get(CSRFHeader)
+	if got == "" {
+		got = r.PostFormValue(CSRFField)
+	}
+	return got != "" && subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
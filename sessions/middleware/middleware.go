@@ -0,0 +1,177 @@
+// Package middleware loads a session for every request of a net/http
+// handler and saves it when it was modified, so that handlers do not have
+// to call Get and Save themselves:
+//
+//	store := mysqlstore.NewMySQLStore(...)
+//	http.ListenAndServe(":8080", middleware.New(store, "session").Handler(mux))
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		session := middleware.Get(r, "session")
+//		visits, _ := session.Int("visits")
+//		session.Set("visits", visits+1)
+//	}
+//
+// The session is saved before the response headers are written, because
+// the cookie is sent with them. Sessions which were only read are saved as
+// well if the store extends their expiry then, see core.Store.SlidingExpiry.
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"net/http"
+	"sync"
+)
+
+// Middleware loads the session named Name from Store for every request.
+type Middleware struct {
+	Store sessions.Store
+	Name  string
+
+	// CSRF, if set, stores a random token in the session and rejects
+	// requests with an unsafe method which do not carry it, see CSRFToken.
+	CSRF bool
+	// CSRFFailure handles the rejected requests. The default replies 403.
+	CSRFFailure http.Handler
+	// ErrorHandler handles errors of loading or saving the session, but
+	// not cookies which cannot be decoded, which get a new session. The
+	// default replies 500.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+func New(store sessions.Store, name string) *Middleware {
+	return &Middleware{
+		Store: store,
+		Name:  name,
+	}
+}
+
+type contextKey string
+
+// Get returns the session loaded by the middleware named name, or nil if
+// there is none.
+func Get(r *http.Request, name string) *Session {
+	session, _ := r.Context().Value(contextKey(name)).(*Session)
+	return session
+}
+
+// Handler returns a handler which loads the session, calls next and saves
+// the session if it was modified or its expiry slides.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := m.Store.Get(r, m.Name)
+		if err != nil && !(isDecodeError(err) && s != nil) {
+			m.error(w, r, err)
+			return
+		}
+		session := newSession(s)
+		if m.CSRF {
+			if err = session.ensureCSRFToken(); err != nil {
+				m.error(w, r, err)
+				return
+			}
+			if !safeMethod(r.Method) && !validCSRFToken(r, session) {
+				m.csrfFailure().ServeHTTP(w, r)
+				return
+			}
+		}
+		r = r.WithContext(context.WithValue(r.Context(), contextKey(m.Name), session))
+		sw := &saveWriter{ResponseWriter: w, m: m, r: r, session: session}
+		next.ServeHTTP(sw, r)
+		sw.save()
+	})
+}
+
+// isDecodeError reports whether err means the session cookie could not be
+// decoded, e.g. because it was tampered with or signed with a retired key.
+// The store then returns a new session, which is used instead, like
+// gorilla/sessions applications do.
+func isDecodeError(err error) bool {
+	var e securecookie.Error
+	return errors.As(err, &e) && e.IsDecode()
+}
+
+func (m *Middleware) error(w http.ResponseWriter, r *http.Request, err error) {
+	if m.ErrorHandler != nil {
+		m.ErrorHandler(w, r, err)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+func (m *Middleware) csrfFailure() http.Handler {
+	if m.CSRFFailure != nil {
+		return m.CSRFFailure
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	})
+}
+
+// slidingStore is implemented by stores which extend the expiry of sessions
+// saved without changes, such as core.Store.
+type slidingStore interface {
+	SlidingExpiry() bool
+}
+
+// saveUnmodified reports whether session has to be saved to extend its
+// expiry although it was not modified.
+func (m *Middleware) saveUnmodified(session *Session) bool {
+	store, ok := m.Store.(slidingStore)
+	return ok && store.SlidingExpiry() && !session.IsNew
+}
+
+// saveWriter saves the session before the headers are written.
+type saveWriter struct {
+	http.ResponseWriter
+	m       *Middleware
+	r       *http.Request
+	session *Session
+
+	once   sync.Once
+	failed bool
+}
+
+// save saves the session if it was modified, or if its expiry slides. If
+// that fails, the response is replaced by the ErrorHandler and what the
+// handler writes is dropped.
+func (w *saveWriter) save() {
+	w.once.Do(func() {
+		if !w.session.Modified() && !w.m.saveUnmodified(w.session) {
+			return
+		}
+		if err := w.session.Save(w.r, w.ResponseWriter); err != nil {
+			w.failed = true
+			w.m.error(w.ResponseWriter, w.r, err)
+		}
+	})
+}
+
+func (w *saveWriter) WriteHeader(code int) {
+	w.save()
+	if !w.failed {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *saveWriter) Write(b []byte) (int, error) {
+	w.save()
+	if w.failed {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *saveWriter) Flush() {
+	w.save()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && !w.failed {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the original writer.
+func (w *saveWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
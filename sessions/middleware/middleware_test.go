@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"context"
+	"github.com/kimiazhu/golib/sessions/core"
+	"github.com/kimiazhu/golib/sessions/memstore"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serve(h http.Handler, method, cookie string, header http.Header) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, "http://localhost:8080/", nil)
+	for k := range header {
+		req.Header.Set(k, header[k][0])
+	}
+	if cookie != "" {
+		req.Header.Add("Cookie", cookie)
+	}
+	rsp := httptest.NewRecorder()
+	h.ServeHTTP(rsp, req)
+	return rsp
+}
+
+func TestMiddleware(t *testing.T) {
+	store := memstore.NewMemStore("/", 3600, []byte("secret-key"))
+	h := New(store, "session").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session := Get(r, "session")
+		if r.URL.Query().Get("visit") != "" {
+			visits, _ := session.Int("visits")
+			session.Set("visits", visits+1)
+		}
+		visits, _ := session.Int("visits")
+		w.Write([]byte(strings.Repeat("x", visits)))
+	}))
+
+	// Unmodified sessions are not saved.
+	rsp := serve(h, "GET", "", nil)
+	if cookie := rsp.Header().Get("Set-Cookie"); cookie != "" {
+		t.Errorf("Expected no cookie; Got %q", cookie)
+	}
+	if store.Len() != 0 {
+		t.Errorf("Expected no stored session; Got %d", store.Len())
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/?visit=1", nil)
+	rsp = httptest.NewRecorder()
+	h.ServeHTTP(rsp, req)
+	cookie := rsp.Header().Get("Set-Cookie")
+	if cookie == "" || rsp.Body.String() != "x" {
+		t.Fatalf("Expected a saved session; Got %q, %q", cookie, rsp.Body.String())
+	}
+
+	// Read-only requests extend the expiry, see TestMiddlewareSlidingExpiry.
+	rsp = serve(h, "GET", cookie, nil)
+	if rsp.Body.String() != "x" || rsp.Header().Get("Set-Cookie") == "" {
+		t.Errorf("Expected the session to load and slide; Got %q, %q", rsp.Body.String(), rsp.Header().Get("Set-Cookie"))
+	}
+}
+
+func TestMiddlewareSlidingExpiry(t *testing.T) {
+	for name, expiration := range map[string]core.Expiration{
+		"max age":      {},
+		"idle timeout": {IdleTimeout: 30 * time.Minute},
+	} {
+		store := memstore.NewMemStore("/", 3600, []byte("secret-key"))
+		store.Expiration = expiration
+		h := New(store, "session").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("login") != "" {
+				Get(r, "session").Set("user", "alice")
+			}
+		}))
+		expiresOn := func() time.Time {
+			list, _, err := store.List(context.Background(), "session", "", 10)
+			if err != nil || len(list) != 1 {
+				t.Fatalf("%s: Expected 1 session; Got %v, %v", name, list, err)
+			}
+			return core.ExpiresOn(list[0])
+		}
+
+		// A new session which was only read is not saved.
+		if rsp := serve(h, "GET", "", nil); rsp.Header().Get("Set-Cookie") != "" || store.Len() != 0 {
+			t.Errorf("%s: Expected no session; Got %v", name, rsp.Header())
+		}
+
+		req, _ := http.NewRequest("GET", "http://localhost:8080/?login=1", nil)
+		rsp := httptest.NewRecorder()
+		h.ServeHTTP(rsp, req)
+		cookie := rsp.Header().Get("Set-Cookie")
+		before := expiresOn()
+
+		// A stored session which was only read gets a later expiry.
+		time.Sleep(10 * time.Millisecond)
+		if rsp = serve(h, "GET", cookie, nil); rsp.Header().Get("Set-Cookie") == "" {
+			t.Errorf("%s: Expected the session to be saved; Got %v", name, rsp.Header())
+		}
+		if after := expiresOn(); !after.After(before) {
+			t.Errorf("%s: Expected the expiry to slide; Got %v, then %v", name, before, after)
+		}
+	}
+}
+
+func TestMiddlewareBadCookie(t *testing.T) {
+	store := memstore.NewMemStore("/", 3600, []byte("secret-key"))
+	h := New(store, "session").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session := Get(r, "session")
+		if !session.IsNew {
+			t.Errorf("Expected a new session")
+		}
+		session.Set("visits", 1)
+	}))
+
+	// A tampered cookie, or one signed with a retired key, gets a new
+	// session rather than an error.
+	rsp := serve(h, "GET", "session=garbage", nil)
+	if rsp.Code != http.StatusOK || rsp.Header().Get("Set-Cookie") == "" {
+		t.Errorf("Expected a new session; Got %d, %v", rsp.Code, rsp.Header())
+	}
+}
+
+func TestMiddlewareSaveError(t *testing.T) {
+	store := memstore.NewMemStore("/", 3600, []byte("secret-key"))
+	m := New(store, "session")
+	m.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		http.Error(w, "save failed", http.StatusServiceUnavailable)
+	}
+	h := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// gob cannot encode a func.
+		Get(r, "session").Set("f", func() {})
+		w.Write([]byte("ok"))
+	}))
+	rsp := serve(h, "GET", "", nil)
+	if rsp.Code != http.StatusServiceUnavailable || strings.Contains(rsp.Body.String(), "ok") {
+		t.Errorf("Expected the error response; Got %d %q", rsp.Code, rsp.Body.String())
+	}
+}
+
+func TestMiddlewareCSRF(t *testing.T) {
+	store := memstore.NewMemStore("/", 3600, []byte("secret-key"))
+	m := New(store, "session")
+	m.CSRF = true
+	var token string
+	h := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = CSRFToken(r, "session")
+	}))
+
+	rsp := serve(h, "GET", "", nil)
+	cookie := rsp.Header().Get("Set-Cookie")
+	if token == "" || cookie == "" {
+		t.Fatalf("Expected a token in a saved session; Got %q, %q", token, cookie)
+	}
+	issued := token
+
+	if rsp = serve(h, "POST", cookie, nil); rsp.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 without a token; Got %d", rsp.Code)
+	}
+	if rsp = serve(h, "POST", cookie, http.Header{CSRFHeader: {"wrong"}}); rsp.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 with a wrong token; Got %d", rsp.Code)
+	}
+	if rsp = serve(h, "POST", cookie, http.Header{CSRFHeader: {issued}}); rsp.Code != http.StatusOK || token != issued {
+		t.Errorf("Expected the token to be accepted; Got %d", rsp.Code)
+	}
+
+	form := url.Values{CSRFField: {issued}}
+	req, _ := http.NewRequest("POST", "http://localhost:8080/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("Cookie", cookie)
+	rsp = httptest.NewRecorder()
+	h.ServeHTTP(rsp, req)
+	if rsp.Code != http.StatusOK {
+		t.Errorf("Expected the form token to be accepted; Got %d", rsp.Code)
+	}
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"github.com/gorilla/sessions"
+	"reflect"
+	"time"
+)
+
+// Session is a session loaded by the middleware. It is saved when its
+// values or options were changed. Values stored by reference, e.g. a map,
+// are compared by reference only, so call Set or MarkModified after
+// changing them in place.
+//
+// Changes made after the handler started writing the response are not
+// saved, the cookie has been sent by then.
+type Session struct {
+	*sessions.Session
+
+	loaded   map[interface{}]interface{}
+	maxAge   int
+	modified bool
+}
+
+func newSession(session *sessions.Session) *Session {
+	s := &Session{
+		Session: session,
+		loaded:  make(map[interface{}]interface{}, len(session.Values)),
+	}
+	for k, v := range session.Values {
+		s.loaded[k] = v
+	}
+	if session.Options != nil {
+		s.maxAge = session.Options.MaxAge
+	}
+	return s
+}
+
+// Modified reports whether the session has to be saved.
+func (s *Session) Modified() bool {
+	if s.modified || len(s.loaded) != len(s.Values) {
+		return true
+	}
+	if s.Options != nil && s.Options.MaxAge != s.maxAge {
+		return true
+	}
+	for k, v := range s.Values {
+		if old, ok := s.loaded[k]; !ok || !reflect.DeepEqual(old, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkModified makes the middleware save the session.
+func (s *Session) MarkModified() {
+	s.modified = true
+}
+
+func (s *Session) Set(key, value interface{}) {
+	s.Values[key] = value
+	s.modified = true
+}
+
+func (s *Session) Delete(key interface{}) {
+	delete(s.Values, key)
+}
+
+// Clear deletes all values and expires the session, e.g. at logout.
+func (s *Session) Clear() {
+	for k := range s.Values {
+		delete(s.Values, k)
+	}
+	s.Options.MaxAge = -1
+}
+
+func (s *Session) String(key interface{}) (string, bool) {
+	v, ok := s.Values[key].(string)
+	return v, ok
+}
+
+func (s *Session) Int(key interface{}) (int, bool) {
+	v, ok := s.Values[key].(int)
+	return v, ok
+}
+
+func (s *Session) Int64(key interface{}) (int64, bool) {
+	v, ok := s.Values[key].(int64)
+	return v, ok
+}
+
+func (s *Session) Float64(key interface{}) (float64, bool) {
+	v, ok := s.Values[key].(float64)
+	return v, ok
+}
+
+func (s *Session) Bool(key interface{}) (bool, bool) {
+	v, ok := s.Values[key].(bool)
+	return v, ok
+}
+
+func (s *Session) Time(key interface{}) (time.Time, bool) {
+	v, ok := s.Values[key].(time.Time)
+	return v, ok
+}
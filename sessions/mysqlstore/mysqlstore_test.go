@@ -8,14 +8,26 @@ package mysqlstore
 
 import (
 	"encoding/gob"
+	"errors"
 	"github.com/gorilla/sessions"
+	"github.com/kimiazhu/golib/sessions/core"
+	"github.com/kimiazhu/golib/sessions/storetest"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 )
 
-//const mysqlTestServer = "testuser:testpw@tcp(localhost:3306)/testdb?parseTime=true&loc=Local"
-const mysqlTestServer = "xgsdk2_zhh:xgsdk2_zhh@tcp(10.20.122.235:3306)/xgsdk2_zhh?parseTime=true&loc=Local"
+// The tests run against the MySQL in MYSQLSTORE_DSN, e.g.
+// testuser:testpw@tcp(localhost:3306)/testdb?parseTime=true&loc=Local
+// The SQL layer is tested against SQLite in sessions/sqlstore.
+func mysqlTestServer(t *testing.T) string {
+	dsn := os.Getenv("MYSQLSTORE_DSN")
+	if dsn == "" {
+		t.Skip("MYSQLSTORE_DSN is not set")
+	}
+	return dsn
+}
 
 type FlashMessage struct {
 	Type    int
@@ -38,9 +50,9 @@ func TestMySQLStore(t *testing.T) {
 
 	// Round 1 ----------------------------------------------------------------
 
-	store, err := NewMySQLStore(mysqlTestServer, "sessionstore", "/", 3600, []byte("secret-key"))
+	store, err := NewMySQLStore(mysqlTestServer(t), "sessionstore", "/", 3600, []byte("secret-key"))
 	if err != nil {
-		t.Fatalf("Error connecting to MySQL: ", err)
+		t.Fatalf("Error connecting to MySQL: %v", err)
 	}
 	defer store.Close()
 
@@ -67,7 +79,7 @@ func TestMySQLStore(t *testing.T) {
 	hdr = rsp.Header()
 	cookies, ok = hdr["Set-Cookie"]
 	if !ok || len(cookies) != 1 {
-		t.Fatalf("No cookies. Header:", hdr)
+		t.Fatalf("No cookies. Header: %v", hdr)
 	}
 
 	// Round 2 ----------------------------------------------------------------
@@ -132,7 +144,7 @@ func TestMySQLStore(t *testing.T) {
 	hdr = rsp.Header()
 	cookies, ok = hdr["Set-Cookie"]
 	if !ok || len(cookies) != 1 {
-		t.Fatalf("No cookies. Header:", hdr)
+		t.Fatalf("No cookies. Header: %v", hdr)
 	}
 
 	// Round 4 ----------------------------------------------------------------
@@ -185,29 +197,42 @@ func TestMySQLStore(t *testing.T) {
 	hdr = rsp.Header()
 	cookies, ok = hdr["Set-Cookie"]
 	if !ok || len(cookies) != 1 {
-		t.Fatalf("No cookies. Header:", hdr)
+		t.Fatalf("No cookies. Header: %v", hdr)
 	}
 
 	// Round 6 ----------------------------------------------------------------
-	// change MaxLength of session
-	//req, err = http.NewRequest("GET", "http://www.example.com", nil)
-	//if err != nil {
-	//	t.Fatal("failed to create request", err)
-	//}
-	//w := httptest.NewRecorder()
-
-	//session, err = store.New(req, "my session")
-	//session.Values["big"] = make([]byte, base64.StdEncoding.DecodedLen(4096*2))
-	//err = session.Save(req, w)
-	//if err == nil {
-	//	t.Fatal("expected an error, got nil")
-	//}
-
-	//store.MaxLength(4096 * 3) // A bit more than the value size to account for encoding overhead.
-	//err = session.Save(req, w)
-	//if err != nil {
-	//	t.Fatal("failed to Save:", err)
-	//}
+	// change MaxSize of session
+	req, _ = http.NewRequest("GET", "http://www.example.com", nil)
+	rsp = httptest.NewRecorder()
+
+	session, err = store.New(req, "my session")
+	session.Values["big"] = make([]byte, 4096*2)
+	if err = session.Save(req, rsp); !errors.Is(err, core.ErrTooLarge) {
+		t.Fatalf("Expected a SizeError; Got %v", err)
+	}
+
+	store.MaxSize = 4096 * 4 // securecookie base64 encodes the value twice.
+	if err = session.Save(req, rsp); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+
+	store.Compression = core.Snappy
+	store.MaxSize = 1024
+	if err = session.Save(req, rsp); err != nil {
+		t.Fatalf("Error saving compressed session: %v", err)
+	}
+}
+
+func TestMySQLStoreConformance(t *testing.T) {
+	dsn := mysqlTestServer(t)
+	storetest.Run(t, func(t *testing.T, maxAge int) sessions.Store {
+		store, err := NewMySQLStore(dsn, "sessionstore", "/", maxAge, []byte("secret-key"))
+		if err != nil {
+			t.Fatalf("Error connecting to MySQL: %v", err)
+		}
+		t.Cleanup(store.Close)
+		return store
+	})
 }
 
 func init() {
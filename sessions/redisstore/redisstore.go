@@ -0,0 +1,332 @@
+// Package redisstore is a Gorilla Sessions backend for Redis.
+//
+// Every session is stored under prefix + ID as a JSON object, so other
+// languages can read it when the store is used with a JSON serializer and
+// Plaintext. Redis removes the keys when the sessions expire.
+//
+// When sessions are bound to users, the IDs of a user's sessions are kept
+// in a set under prefix + "user_" + user ID. Session IDs never contain an
+// underscore, so the sets are told apart from the sessions by their key.
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/kimiazhu/golib/sessions/core"
+	"github.com/redis/go-redis/v9"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPrefix is prepended to the session ID to build the key.
+const DefaultPrefix = "session_"
+
+type RedisStore struct {
+	*core.Store
+	backend *redisBackend
+}
+
+type redisBackend struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// redisRecord is the JSON stored for a session.
+type redisRecord struct {
+	Data       []byte    `json:"session_data"`
+	UserID     string    `json:"user_id,omitempty"`
+	CreatedOn  time.Time `json:"created_on"`
+	ModifiedOn time.Time `json:"modified_on"`
+	ExpiresOn  time.Time `json:"expires_on"`
+	Version    int64     `json:"version,omitempty"`
+}
+
+func NewRedisStore(addr string, password string, path string, maxAge int, keyPairs ...[]byte) *RedisStore {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+	})
+	return NewRedisStoreFromClient(client, DefaultPrefix, path, maxAge, keyPairs...)
+}
+
+func NewRedisStoreFromClient(client redis.UniversalClient, prefix string, path string, maxAge int, keyPairs ...[]byte) *RedisStore {
+	backend := &redisBackend{
+		client: client,
+		prefix: prefix,
+	}
+	return &RedisStore{
+		Store:   core.NewStore(backend, path, maxAge, keyPairs...),
+		backend: backend,
+	}
+}
+
+func (s *RedisStore) Close() {
+	s.backend.client.Close()
+}
+
+func (b *redisBackend) Load(ctx context.Context, id string) (*core.Record, error) {
+	data, err := b.client.Get(ctx, b.prefix+id).Bytes()
+	if err == redis.Nil {
+		return nil, core.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return unmarshal(id, data)
+}
+
+func (b *redisBackend) Insert(ctx context.Context, rec *core.Record) error {
+	data, ttl, err := b.marshal(rec)
+	if err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		// already expired, e.g. MaxAge < 0
+		return nil
+	}
+	ok, err := b.client.SetNX(ctx, b.prefix+rec.ID, data, ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return core.ErrDuplicateID
+	}
+	return b.index(ctx, rec, ttl)
+}
+
+func (b *redisBackend) Save(ctx context.Context, rec *core.Record) error {
+	data, ttl, err := b.marshal(rec)
+	if err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		return b.Delete(ctx, rec.ID)
+	}
+	ok, err := b.client.SetXX(ctx, b.prefix+rec.ID, data, ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return core.ErrNotFound
+	}
+	return b.index(ctx, rec, ttl)
+}
+
+// Swap sets the key of rec in a transaction which fails if the key was
+// changed after its version was checked.
+func (b *redisBackend) Swap(ctx context.Context, rec *core.Record, version int64) error {
+	data, ttl, err := b.marshal(rec)
+	if err != nil {
+		return err
+	}
+	key := b.prefix + rec.ID
+	err = b.client.Watch(ctx, func(tx *redis.Tx) error {
+		stored, err := tx.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			return core.ErrNotFound
+		} else if err != nil {
+			return err
+		}
+		old, err := unmarshal(rec.ID, stored)
+		if err != nil {
+			return err
+		}
+		if old.Version != version {
+			return core.ErrVersionConflict
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if ttl <= 0 {
+				pipe.Del(ctx, key)
+			} else {
+				pipe.Set(ctx, key, data, ttl)
+			}
+			return nil
+		})
+		return err
+	}, key)
+	if err == redis.TxFailedErr {
+		return core.ErrVersionConflict
+	} else if err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	return b.index(ctx, rec, ttl)
+}
+
+func (b *redisBackend) Delete(ctx context.Context, id string) error {
+	return b.client.Del(ctx, b.prefix+id).Err()
+}
+
+// Count scans the keys with the prefix, Redis has already dropped the
+// expired ones.
+func (b *redisBackend) Count(ctx context.Context) (int64, error) {
+	var n int64
+	iter := b.client.Scan(ctx, 0, b.prefix+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		if !b.isUserKey(iter.Val()) {
+			n++
+		}
+	}
+	return n, iter.Err()
+}
+
+// List pages through the keys with SCAN, the cursor is the SCAN cursor.
+// Pages may be shorter or longer than limit.
+func (b *redisBackend) List(ctx context.Context, cursor string, limit int) ([]*core.Record, string, error) {
+	var c uint64
+	if cursor != "" {
+		var err error
+		if c, err = strconv.ParseUint(cursor, 10, 64); err != nil {
+			return nil, "", err
+		}
+	}
+	all, c, err := b.client.Scan(ctx, c, b.prefix+"*", int64(limit)).Result()
+	if err != nil {
+		return nil, "", err
+	}
+	keys := all[:0]
+	for _, k := range all {
+		if !b.isUserKey(k) {
+			keys = append(keys, k)
+		}
+	}
+	next := ""
+	if c != 0 {
+		next = strconv.FormatUint(c, 10)
+	}
+	if len(keys) == 0 {
+		return nil, next, nil
+	}
+	values, err := b.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, "", err
+	}
+	recs := make([]*core.Record, 0, len(keys))
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			// expired since SCAN
+			continue
+		}
+		rec, err := unmarshal(keys[i][len(b.prefix):], []byte(data))
+		if err != nil {
+			return nil, "", err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, next, nil
+}
+
+// index adds the ID of rec to the set of its user. The set lives as long
+// as the newest session of the user, deleted sessions are removed from it
+// lazily by ListByUser.
+func (b *redisBackend) index(ctx context.Context, rec *core.Record, ttl time.Duration) error {
+	if rec.UserID == "" {
+		return nil
+	}
+	key := b.userKey(rec.UserID)
+	pipe := b.client.TxPipeline()
+	pipe.SAdd(ctx, key, rec.ID)
+	if cur, err := b.client.PTTL(ctx, key).Result(); err != nil || cur < ttl {
+		pipe.PExpire(ctx, key, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// ListByUser returns the records of userID ordered by creation time.
+func (b *redisBackend) ListByUser(ctx context.Context, userID string) ([]*core.Record, error) {
+	key := b.userKey(userID)
+	ids, err := b.client.SMembers(ctx, key).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = b.prefix + id
+	}
+	values, err := b.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	var recs []*core.Record
+	var stale []interface{}
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			stale = append(stale, ids[i])
+			continue
+		}
+		rec, err := unmarshal(ids[i], []byte(data))
+		if err != nil {
+			return nil, err
+		}
+		if rec.UserID != userID {
+			// the session was saved for another user since
+			stale = append(stale, ids[i])
+			continue
+		}
+		recs = append(recs, rec)
+	}
+	if len(stale) > 0 {
+		if err = b.client.SRem(ctx, key, stale...).Err(); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].CreatedOn.Equal(recs[j].CreatedOn) {
+			return recs[i].ID < recs[j].ID
+		}
+		return recs[i].CreatedOn.Before(recs[j].CreatedOn)
+	})
+	return recs, nil
+}
+
+// ListIDsByUser returns the records of userID ordered by creation time.
+// Expired records are gone already.
+func (b *redisBackend) ListIDsByUser(ctx context.Context, userID string) ([]*core.Record, error) {
+	return b.ListByUser(ctx, userID)
+}
+
+func (b *redisBackend) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	recs, err := b.ListByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	keys := []string{b.userKey(userID)}
+	for _, rec := range recs {
+		keys = append(keys, b.prefix+rec.ID)
+	}
+	if err = b.client.Del(ctx, keys...).Err(); err != nil {
+		return 0, err
+	}
+	return int64(len(recs)), nil
+}
+
+func (b *redisBackend) userKey(userID string) string {
+	return b.prefix + "user_" + userID
+}
+
+func (b *redisBackend) isUserKey(key string) bool {
+	return strings.HasPrefix(key, b.prefix+"user_")
+}
+
+// marshal returns the JSON of rec and the time until it expires.
+func (b *redisBackend) marshal(rec *core.Record) ([]byte, time.Duration, error) {
+	data, err := json.Marshal(redisRecord{rec.Data, rec.UserID, rec.CreatedOn, rec.ModifiedOn, rec.ExpiresOn, rec.Version})
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, time.Until(rec.ExpiresOn), nil
+}
+
+func unmarshal(id string, data []byte) (*core.Record, error) {
+	var r redisRecord
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &core.Record{ID: id, Data: r.Data, UserID: r.UserID, CreatedOn: r.CreatedOn, ModifiedOn: r.ModifiedOn, ExpiresOn: r.ExpiresOn, Version: r.Version}, nil
+}
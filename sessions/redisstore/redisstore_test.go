@@ -0,0 +1,176 @@
+package redisstore
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/sessions"
+	"github.com/kimiazhu/golib/sessions/storetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRedisStore(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Error starting miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	store := NewRedisStore(mr.Addr(), "", "/", 3600, []byte("secret-key"))
+	defer store.Close()
+
+	// Round 1: a new session is saved.
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	rsp := httptest.NewRecorder()
+	session, err := store.Get(req, "session-key")
+	if err != nil {
+		t.Fatalf("Error getting session: %v", err)
+	}
+	session.AddFlash("foo")
+	if err = sessions.Save(req, rsp); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	cookies := rsp.Header()["Set-Cookie"]
+	if len(cookies) != 1 {
+		t.Fatalf("No cookies. Header: %v", rsp.Header())
+	}
+	key := DefaultPrefix + session.ID
+	if ttl := mr.TTL(key); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("Expected TTL of at most an hour; Got %v", ttl)
+	}
+
+	// Round 2: the session is loaded and deleted.
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Add("Cookie", cookies[0])
+	rsp = httptest.NewRecorder()
+	if session, err = store.Get(req, "session-key"); err != nil {
+		t.Fatalf("Error getting session: %v", err)
+	}
+	if flashes := session.Flashes(); len(flashes) != 1 || flashes[0] != "foo" {
+		t.Fatalf("Expected foo; Got %v", flashes)
+	}
+	if err = store.Delete(req, rsp, session); err != nil {
+		t.Fatalf("Error deleting session: %v", err)
+	}
+	if mr.Exists(key) {
+		t.Errorf("Expected %s to be deleted", key)
+	}
+
+	// Round 3: Redis drops expired sessions.
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	rsp = httptest.NewRecorder()
+	session, _ = store.Get(req, "session-key")
+	if err = sessions.Save(req, rsp); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	mr.FastForward(2 * time.Hour)
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Add("Cookie", rsp.Header()["Set-Cookie"][0])
+	if session, err = store.Get(req, "session-key"); err != nil {
+		t.Fatalf("Error getting session: %v", err)
+	}
+	if !session.IsNew {
+		t.Errorf("Expected a new session after expiry")
+	}
+}
+
+func TestRedisStoreUserSessions(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Error starting miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	store := NewRedisStore(mr.Addr(), "", "/", 3600, []byte("secret-key"))
+	defer store.Close()
+	store.UserKey = "user_id"
+	if err = store.SetMaxSessionsPerUser(2); err != nil {
+		t.Fatalf("Error limiting user sessions: %v", err)
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		session, _ := store.New(req, "session-key")
+		session.Values["user_id"] = "alice"
+		if err = store.Save(req, httptest.NewRecorder(), session); err != nil {
+			t.Fatalf("Error saving session: %v", err)
+		}
+	}
+	list, err := store.UserSessions(ctx, "session-key", "alice")
+	if err != nil || len(list) != 2 {
+		t.Fatalf("Expected 2 sessions; Got %d, %v", len(list), err)
+	}
+	if members, _ := mr.Members(DefaultPrefix + "user_alice"); len(members) != 2 {
+		t.Errorf("Expected 2 members in the user set; Got %v", members)
+	}
+
+	if n, err := store.Count(ctx); err != nil || n != 2 {
+		t.Errorf("Expected a count of 2; Got %d, %v", n, err)
+	}
+
+	n, err := store.RevokeUser(ctx, "alice")
+	if err != nil || n != 2 {
+		t.Errorf("Expected 2 revoked sessions; Got %d, %v", n, err)
+	}
+	if mr.Exists(DefaultPrefix + "user_alice") {
+		t.Errorf("Expected the user set to be deleted")
+	}
+}
+
+func TestRedisStoreRegenerate(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Error starting miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	store := NewRedisStore(mr.Addr(), "", "/", 3600, []byte("secret-key"))
+	defer store.Close()
+	store.RegenerateGrace = 10 * time.Second
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	session, _ := store.New(req, "session-key")
+	session.Values["foo"] = "bar"
+	if err = store.Save(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	oldID := session.ID
+	if err = store.Regenerate(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error regenerating session: %v", err)
+	}
+	if ttl := mr.TTL(DefaultPrefix + oldID); ttl <= 0 || ttl > 10*time.Second {
+		t.Errorf("Expected the old ID to expire within 10s; Got %v", ttl)
+	}
+	moved, err := store.Lookup(context.Background(), "session-key", session.ID)
+	if err != nil || moved.Values["foo"] != "bar" {
+		t.Errorf("Expected the values under the new ID; Got %v, %v", moved, err)
+	}
+}
+
+func TestRedisStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, maxAge int) sessions.Store {
+		mr, err := miniredis.Run()
+		if err != nil {
+			t.Fatalf("Error starting miniredis: %v", err)
+		}
+		t.Cleanup(mr.Close)
+		store := NewRedisStore(mr.Addr(), "", "/", maxAge, []byte("secret-key"))
+		t.Cleanup(store.Close)
+		return store
+	})
+}
+
+func TestRedisStoreConflicts(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Error starting miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	store := NewRedisStore(mr.Addr(), "", "/", 3600, []byte("secret-key"))
+	defer store.Close()
+	storetest.RunConflicts(t, store.Store)
+}
@@ -0,0 +1,174 @@
+package sqlstore
+
+import (
+	"container/list"
+	"github.com/kimiazhu/golib/sessions/core"
+	"sync"
+	"time"
+)
+
+// Notifier spreads cache invalidations between the instances sharing a
+// session table, e.g. through Redis pub/sub.
+type Notifier interface {
+	// Publish tells the other instances that the session with id has
+	// changed or was deleted.
+	Publish(id string) error
+	// Subscribe registers f to be called with every published id. It is
+	// fine if f also receives the ids published by this instance.
+	Subscribe(f func(id string)) error
+}
+
+// SetCache puts an LRU cache of at most size sessions in front of the
+// table. Cached sessions are used for at most ttl, or until they are saved
+// or deleted through this store. When the table is shared by several
+// instances, pass a notifier so that they drop each other's stale
+// entries, or keep ttl short. A size <= 0 disables the cache.
+//
+// SetCache must be called before the store is used.
+func (s *SQLStore) SetCache(size int, ttl time.Duration, notifier Notifier) error {
+	b := s.backend
+	if size <= 0 {
+		b.cache, b.notifier = nil, nil
+		return nil
+	}
+	c := newRowCache(size, ttl)
+	if notifier != nil {
+		if err := notifier.Subscribe(c.remove); err != nil {
+			return err
+		}
+	}
+	b.cache, b.notifier = c, notifier
+	return nil
+}
+
+// cached stores rec in the cache and tells the other instances to drop
+// their copy.
+func (b *sqlBackend) cached(rec *core.Record) {
+	if b.cache == nil {
+		return
+	}
+	b.cache.add(rec)
+	b.publish(rec.ID)
+}
+
+// uncached removes id from the cache and tells the other instances to do
+// the same.
+func (b *sqlBackend) uncached(id string) {
+	if b.cache == nil {
+		return
+	}
+	b.cache.remove(id)
+	b.publish(id)
+}
+
+func (b *sqlBackend) publish(id string) {
+	if b.notifier == nil {
+		return
+	}
+	if err := b.notifier.Publish(id); err != nil {
+		b.reportFailure("publish session invalidation", err)
+	}
+}
+
+type cacheEntry struct {
+	rec     *core.Record
+	addedOn time.Time
+}
+
+// rowCache is a size and TTL bounded LRU cache of session records.
+type rowCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	ll    *list.List
+	items map[string]*list.Element
+	// gens counts the changes of the IDs hashing to each slot, so that a
+	// row read before a change is not cached after it, see addLoaded.
+	gens [256]uint64
+}
+
+func newRowCache(size int, ttl time.Duration) *rowCache {
+	return &rowCache{
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (c *rowCache) get(id string) (*core.Record, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[id]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*cacheEntry)
+	if c.ttl > 0 && time.Since(entry.addedOn) > c.ttl {
+		c.ll.Remove(e)
+		delete(c.items, id)
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return entry.rec, true
+}
+
+// generation returns the change counter of id, to be passed to addLoaded.
+func (c *rowCache) generation(id string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return *c.gen(id)
+}
+
+// gen returns the change counter of id. IDs share counters, which only
+// costs a skipped addLoaded now and then.
+func (c *rowCache) gen(id string) *uint64 {
+	h := uint32(2166136261)
+	for i := 0; i < len(id); i++ {
+		h = (h ^ uint32(id[i])) * 16777619
+	}
+	return &c.gens[h%uint32(len(c.gens))]
+}
+
+// add caches rec, which was just written.
+func (c *rowCache) add(rec *core.Record) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.gen(rec.ID)++
+	c.put(rec)
+}
+
+// addLoaded caches rec, which was read from the table, unless the row was
+// written or invalidated since generation returned gen.
+func (c *rowCache) addLoaded(rec *core.Record, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if *c.gen(rec.ID) != gen {
+		return
+	}
+	c.put(rec)
+}
+
+func (c *rowCache) put(rec *core.Record) {
+	if e, ok := c.items[rec.ID]; ok {
+		e.Value = &cacheEntry{rec, time.Now()}
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[rec.ID] = c.ll.PushFront(&cacheEntry{rec, time.Now()})
+	if c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*cacheEntry).rec.ID)
+	}
+}
+
+func (c *rowCache) remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.gen(id)++
+	if e, ok := c.items[id]; ok {
+		c.ll.Remove(e)
+		delete(c.items, id)
+	}
+}
@@ -0,0 +1,65 @@
+package sqlstore
+
+import (
+	"github.com/kimiazhu/golib/sessions/core"
+	"testing"
+	"time"
+)
+
+func TestRowCacheEviction(t *testing.T) {
+	c := newRowCache(2, 0)
+	c.add(&core.Record{ID: "a"})
+	c.add(&core.Record{ID: "b"})
+	// a becomes the most recently used, so b is evicted
+	if _, ok := c.get("a"); !ok {
+		t.Fatalf("Expected a to be cached")
+	}
+	c.add(&core.Record{ID: "c"})
+	if _, ok := c.get("b"); ok {
+		t.Errorf("Expected b to be evicted")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Errorf("Expected a to be cached")
+	}
+	c.remove("a")
+	if _, ok := c.get("a"); ok {
+		t.Errorf("Expected a to be removed")
+	}
+}
+
+func TestRowCacheGeneration(t *testing.T) {
+	c := newRowCache(10, 0)
+	// The row is read, then deleted before the read is cached.
+	gen := c.generation("a")
+	c.remove("a")
+	c.addLoaded(&core.Record{ID: "a"}, gen)
+	if _, ok := c.get("a"); ok {
+		t.Errorf("Expected the deleted row not to be cached")
+	}
+
+	// The row is read, then saved before the read is cached.
+	gen = c.generation("a")
+	c.add(&core.Record{ID: "a", Version: 2})
+	c.addLoaded(&core.Record{ID: "a", Version: 1}, gen)
+	if rec, ok := c.get("a"); !ok || rec.Version != 2 {
+		t.Errorf("Expected version 2 to be cached; Got %v", rec)
+	}
+
+	gen = c.generation("b")
+	c.addLoaded(&core.Record{ID: "b"}, gen)
+	if _, ok := c.get("b"); !ok {
+		t.Errorf("Expected b to be cached")
+	}
+}
+
+func TestRowCacheTTL(t *testing.T) {
+	c := newRowCache(10, 10*time.Millisecond)
+	c.add(&core.Record{ID: "a"})
+	if _, ok := c.get("a"); !ok {
+		t.Fatalf("Expected a to be cached")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.get("a"); ok {
+		t.Errorf("Expected a to be expired")
+	}
+}
@@ -0,0 +1,115 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	defaultCleanupInterval = 10 * time.Minute
+	defaultCleanupBatch    = 1000
+)
+
+// StartCleanup starts a goroutine that deletes expired sessions every
+// interval, removing at most batch rows per statement so the table is not
+// locked for long. An interval <= 0 uses the default of 10 minutes, a
+// batch <= 0 the default of 1000 rows. Calling it
+// again restarts the goroutine with the new settings. After every run the
+// number of sessions is reported to the Observer, if any.
+func (s *SQLStore) StartCleanup(interval time.Duration, batch int) {
+	s.StopCleanup()
+
+	s.cleanupMu.Lock()
+	defer s.cleanupMu.Unlock()
+	if interval <= 0 {
+		interval = defaultCleanupInterval
+	}
+	if batch > 0 {
+		s.cleanupBatch = batch
+	}
+	quit, done := make(chan struct{}), make(chan struct{})
+	s.cleanupQuit, s.cleanupDone = quit, done
+	go s.cleanup(interval, s.batchSize(), quit, done)
+}
+
+// StopCleanup stops the goroutine started by StartCleanup and waits for it
+// to exit. It is called by Close.
+func (s *SQLStore) StopCleanup() {
+	s.cleanupMu.Lock()
+	defer s.cleanupMu.Unlock()
+	if s.cleanupQuit == nil {
+		return
+	}
+	close(s.cleanupQuit)
+	<-s.cleanupDone
+	s.cleanupQuit, s.cleanupDone = nil, nil
+}
+
+// DeleteExpired deletes all expired sessions in batches and returns the
+// number of deleted rows. It is meant for cron jobs when the cleanup
+// goroutine is not used.
+func (s *SQLStore) DeleteExpired(ctx context.Context) (int64, error) {
+	s.cleanupMu.Lock()
+	batch := s.batchSize()
+	s.cleanupMu.Unlock()
+	return s.deleteExpired(ctx, batch)
+}
+
+func (s *SQLStore) batchSize() int {
+	if s.cleanupBatch <= 0 {
+		return defaultCleanupBatch
+	}
+	return s.cleanupBatch
+}
+
+func (s *SQLStore) deleteExpired(ctx context.Context, batch int) (int64, error) {
+	var total int64
+	for {
+		res, err := s.backend.stmtDeleteExpired.ExecContext(ctx, time.Now(), batch)
+		if err != nil {
+			return total, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n < int64(batch) {
+			return total, nil
+		}
+	}
+}
+
+func (s *SQLStore) cleanup(interval time.Duration, batch int, quit <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			if _, err := s.deleteExpired(ctx, batch); err != nil {
+				if ctx.Err() == nil {
+					s.ReportFailure("delete expired sessions", err)
+				}
+			} else if s.Observer != nil {
+				// Let the observer follow the size of the table.
+				if _, err = s.Count(ctx); err != nil && ctx.Err() == nil {
+					s.ReportFailure("count sessions", err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package sqlstore
+
+import (
+	"context"
+	"github.com/kimiazhu/golib/sessions/core"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// insertSessions inserts n sessions which expire at expiresOn.
+func insertSessions(t *testing.T, store *SQLStore, prefix string, n int, expiresOn time.Time) {
+	now := time.Now()
+	for i := 0; i < n; i++ {
+		rec := &core.Record{ID: prefix + strconv.Itoa(i), CreatedOn: now, ModifiedOn: now, ExpiresOn: expiresOn, Version: 1}
+		if err := store.backend.Insert(context.Background(), rec); err != nil {
+			t.Fatalf("Error inserting session: %v", err)
+		}
+	}
+}
+
+func TestSQLStoreDeleteExpired(t *testing.T) {
+	store, err := NewSQLStoreFromConnection(openSQLite(t), SQLite, "sessionstore", "/", 3600, []byte("secret-key"))
+	if err != nil {
+		t.Fatalf("Error creating store: %v", err)
+	}
+	defer store.Close()
+	insertSessions(t, store, "expired", 5, time.Now().Add(-time.Minute))
+	insertSessions(t, store, "active", 1, time.Now().Add(time.Hour))
+
+	// Several batches are needed.
+	store.cleanupBatch = 2
+	n, err := store.DeleteExpired(context.Background())
+	if err != nil || n != 5 {
+		t.Errorf("Expected 5 deleted sessions; Got %d, %v", n, err)
+	}
+	if _, err = store.backend.Load(context.Background(), "active0"); err != nil {
+		t.Errorf("Expected the active session to be kept; Got %v", err)
+	}
+}
+
+func TestSQLStoreCleanup(t *testing.T) {
+	store, err := NewSQLStoreFromConnection(openSQLite(t), SQLite, "sessionstore", "/", 3600, []byte("secret-key"))
+	if err != nil {
+		t.Fatalf("Error creating store: %v", err)
+	}
+	defer store.Close()
+	metrics := &core.Metrics{}
+	store.Observer = metrics
+	insertSessions(t, store, "expired", 3, time.Now().Add(-time.Minute))
+	insertSessions(t, store, "active", 1, time.Now().Add(time.Hour))
+
+	// The number of sessions is reported after the first run.
+	store.StartCleanup(10*time.Millisecond, 0)
+	deadline := time.Now().Add(5 * time.Second)
+	for metrics.Snapshot().Sessions != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	store.StopCleanup()
+	if _, err = store.backend.Load(context.Background(), "expired0"); err != core.ErrNotFound {
+		t.Errorf("Expected the expired sessions to be deleted; Got %v", err)
+	}
+
+	// A zero interval uses the default instead of panicking.
+	store.StartCleanup(0, 0)
+	store.StopCleanup()
+}
@@ -0,0 +1,49 @@
+package sqlstore
+
+import (
+	"strings"
+)
+
+// Dialect hides the differences between SQL databases from SQLStore.
+type Dialect interface {
+	// Quote encloses an identifier such as the table name.
+	Quote(name string) string
+	// Placeholder returns the bind parameter for the n-th argument,
+	// counting from 1.
+	Placeholder(n int) string
+	// Migrations returns the versions of the session table, see Migrate.
+	// Version 1 creates the table and its indexes.
+	Migrations(table string) []Migration
+	// Legacy returns the statements which bring a table created before
+	// schema versions were recorded to version 1, apart from the user_id
+	// column and the MySQL index on expires_on, which are added by
+	// Migrate.
+	Legacy(table string) []string
+	// DeleteExpired returns a statement deleting at most the second
+	// argument rows whose expires_on is before the first argument.
+	DeleteExpired(table string) string
+	// IsPermissionDenied reports whether err means the user is not allowed
+	// to run the statement, e.g. CREATE TABLE.
+	IsPermissionDenied(err error) bool
+	// IsDuplicateKey reports whether err is a primary or unique key
+	// violation.
+	IsDuplicateKey(err error) bool
+}
+
+// placeholders returns the bind parameters from..from+n-1 separated by
+// commas.
+func placeholders(d Dialect, from, n int) string {
+	p := make([]string, n)
+	for i := range p {
+		p[i] = d.Placeholder(from + i)
+	}
+	return strings.Join(p, ", ")
+}
+
+// subqueryDeleteExpired builds DeleteExpired for databases without
+// DELETE ... LIMIT.
+func subqueryDeleteExpired(d Dialect, table string) string {
+	t := d.Quote(table)
+	return "DELETE FROM " + t + " WHERE id IN (SELECT id FROM " + t +
+		" WHERE expires_on < " + d.Placeholder(1) + " LIMIT " + d.Placeholder(2) + ")"
+}
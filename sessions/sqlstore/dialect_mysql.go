@@ -0,0 +1,96 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"strings"
+)
+
+// MySQL is the dialect for github.com/go-sql-driver/mysql. The DSN must
+// contain parseTime=true.
+var MySQL Dialect = mysqlDialect{}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Quote(name string) string {
+	return "`" + strings.Trim(name, "`") + "`"
+}
+
+func (mysqlDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (d mysqlDialect) Migrations(table string) []Migration {
+	return []Migration{
+		{1, []string{"CREATE TABLE IF NOT EXISTS " +
+			d.Quote(table) + " (id VARCHAR(64) NOT NULL, " +
+			"session_data LONGBLOB, " +
+			"user_id VARCHAR(255) NULL, " +
+			"created_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+			"modified_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP, " +
+			"expires_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+			"PRIMARY KEY(`id`), KEY(`user_id`), KEY(`expires_on`)) ENGINE=InnoDB"}},
+		{2, []string{"ALTER TABLE " + d.Quote(table) + " ADD COLUMN version BIGINT NOT NULL DEFAULT 0"}},
+	}
+}
+
+// Legacy converts the INT AUTO_INCREMENT id of the first versions to
+// VARCHAR, numeric IDs stay valid until they expire, and replaces the
+// DEFAULT 0 timestamps which are rejected in strict mode.
+func (d mysqlDialect) Legacy(table string) []string {
+	return []string{"ALTER TABLE " + d.Quote(table) + " MODIFY id VARCHAR(64) NOT NULL, " +
+		"MODIFY created_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+		"MODIFY modified_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP, " +
+		"MODIFY expires_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP"}
+}
+
+// LockMigration takes a named lock, which is held by the connection.
+func (mysqlDialect) LockMigration(ctx context.Context, conn *sql.Conn, table string) (func(error) error, error) {
+	name := "sqlstore_migrate_" + strings.Trim(table, "`")
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 60)", name).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if locked.Int64 != 1 {
+		return nil, errors.New("timeout waiting for lock " + name)
+	}
+	return func(error) error {
+		_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", name)
+		return err
+	}, nil
+}
+
+// addExpiresIndex adds the index on expires_on, which DeleteExpired needs,
+// to tables created before it existed, unless it was added by hand.
+func (d mysqlDialect) addExpiresIndex(ctx context.Context, db execer, table string) error {
+	var n int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.statistics "+
+		"WHERE table_schema = DATABASE() AND table_name = ? AND column_name = 'expires_on'",
+		strings.Trim(table, "`")).Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = db.ExecContext(ctx, d.expiresIndexDDL(table))
+	return err
+}
+
+func (d mysqlDialect) expiresIndexDDL(table string) string {
+	return "ALTER TABLE " + d.Quote(table) + " ADD INDEX (expires_on)"
+}
+
+func (d mysqlDialect) DeleteExpired(table string) string {
+	return "DELETE FROM " + d.Quote(table) + " WHERE expires_on < ? LIMIT ?"
+}
+
+func (mysqlDialect) IsPermissionDenied(err error) bool {
+	// Error 1142 means permission denied for create command
+	var e *mysql.MySQLError
+	return errors.As(err, &e) && e.Number == 1142
+}
+
+func (mysqlDialect) IsDuplicateKey(err error) bool {
+	var e *mysql.MySQLError
+	return errors.As(err, &e) && e.Number == 1062
+}
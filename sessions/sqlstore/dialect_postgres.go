@@ -0,0 +1,80 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Postgres is the dialect for PostgreSQL. It works with any driver whose
+// errors report their SQLSTATE through a SQLState() method, such as
+// github.com/lib/pq and github.com/jackc/pgx.
+var Postgres Dialect = postgresDialect{}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Quote(name string) string {
+	return `"` + strings.Trim(name, `"`) + `"`
+}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (d postgresDialect) Migrations(table string) []Migration {
+	return []Migration{
+		{1, []string{
+			"CREATE TABLE IF NOT EXISTS " + d.Quote(table) + " (id VARCHAR(64) NOT NULL PRIMARY KEY, " +
+				"session_data BYTEA, " +
+				"user_id VARCHAR(255), " +
+				"created_on TIMESTAMPTZ NOT NULL, " +
+				"modified_on TIMESTAMPTZ NOT NULL, " +
+				"expires_on TIMESTAMPTZ NOT NULL)",
+			"CREATE INDEX IF NOT EXISTS " + d.Quote(strings.Trim(table, `"`)+"_expires_on_idx") +
+				" ON " + d.Quote(table) + " (expires_on)",
+			"CREATE INDEX IF NOT EXISTS " + d.Quote(strings.Trim(table, `"`)+"_user_id_idx") +
+				" ON " + d.Quote(table) + " (user_id)",
+		}},
+		{2, []string{"ALTER TABLE " + d.Quote(table) + " ADD COLUMN version BIGINT NOT NULL DEFAULT 0"}},
+	}
+}
+
+func (postgresDialect) Legacy(table string) []string {
+	return nil
+}
+
+// LockMigration takes a session-level advisory lock keyed by the table.
+func (postgresDialect) LockMigration(ctx context.Context, conn *sql.Conn, table string) (func(error) error, error) {
+	name := "sqlstore_migrate_" + strings.Trim(table, `"`)
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock(hashtext($1))", name); err != nil {
+		return nil, err
+	}
+	return func(error) error {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock(hashtext($1))", name)
+		return err
+	}, nil
+}
+
+func (d postgresDialect) DeleteExpired(table string) string {
+	return subqueryDeleteExpired(d, table)
+}
+
+func (postgresDialect) IsPermissionDenied(err error) bool {
+	return sqlState(err) == "42501"
+}
+
+func (postgresDialect) IsDuplicateKey(err error) bool {
+	return sqlState(err) == "23505"
+}
+
+func sqlState(err error) string {
+	var e interface {
+		SQLState() string
+	}
+	if errors.As(err, &e) {
+		return e.SQLState()
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// SQLite is the dialect for SQLite 3.24 or newer. It does not depend on a
+// particular driver, errors are classified by their message.
+var SQLite Dialect = sqliteDialect{}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Quote(name string) string {
+	return `"` + strings.Trim(name, `"`) + `"`
+}
+
+func (sqliteDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (d sqliteDialect) Migrations(table string) []Migration {
+	return []Migration{
+		{1, []string{
+			"CREATE TABLE IF NOT EXISTS " + d.Quote(table) + " (id TEXT NOT NULL PRIMARY KEY, " +
+				"session_data BLOB, " +
+				"user_id VARCHAR(255), " +
+				"created_on TIMESTAMP NOT NULL, " +
+				"modified_on TIMESTAMP NOT NULL, " +
+				"expires_on TIMESTAMP NOT NULL)",
+			"CREATE INDEX IF NOT EXISTS " + d.Quote(strings.Trim(table, `"`)+"_expires_on_idx") +
+				" ON " + d.Quote(table) + " (expires_on)",
+			"CREATE INDEX IF NOT EXISTS " + d.Quote(strings.Trim(table, `"`)+"_user_id_idx") +
+				" ON " + d.Quote(table) + " (user_id)",
+		}},
+		{2, []string{"ALTER TABLE " + d.Quote(table) + " ADD COLUMN version INTEGER NOT NULL DEFAULT 0"}},
+	}
+}
+
+func (sqliteDialect) Legacy(table string) []string {
+	return nil
+}
+
+// LockMigration starts a write transaction, which SQLite allows one of at
+// a time. The migration is committed by unlock, or rolled back if it
+// failed.
+func (sqliteDialect) LockMigration(ctx context.Context, conn *sql.Conn, table string) (func(error) error, error) {
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return nil, err
+	}
+	return func(migrateErr error) error {
+		end := "COMMIT"
+		if migrateErr != nil {
+			end = "ROLLBACK"
+		}
+		_, err := conn.ExecContext(ctx, end)
+		return err
+	}, nil
+}
+
+func (d sqliteDialect) DeleteExpired(table string) string {
+	return subqueryDeleteExpired(d, table)
+}
+
+func (sqliteDialect) IsPermissionDenied(err error) bool {
+	// SQLite has no privileges, a read-only database is the closest match.
+	return err != nil && strings.Contains(err.Error(), "readonly database")
+}
+
+func (sqliteDialect) IsDuplicateKey(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
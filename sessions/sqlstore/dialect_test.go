@@ -0,0 +1,61 @@
+package sqlstore
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeleteExpired(t *testing.T) {
+	tests := []struct {
+		dialect  Dialect
+		expected string
+	}{
+		{MySQL, "DELETE FROM `sessions` WHERE expires_on < ? LIMIT ?"},
+		{Postgres, `DELETE FROM "sessions" WHERE id IN (SELECT id FROM "sessions" WHERE expires_on < $1 LIMIT $2)`},
+		{SQLite, `DELETE FROM "sessions" WHERE id IN (SELECT id FROM "sessions" WHERE expires_on < ? LIMIT ?)`},
+	}
+	for _, test := range tests {
+		if got := test.dialect.DeleteExpired("sessions"); got != test.expected {
+			t.Errorf("%T: Expected %s; Got %s", test.dialect, test.expected, got)
+		}
+	}
+}
+
+func TestDDL(t *testing.T) {
+	ddl := DDL(SQLite, "sessions")
+	for _, expected := range []string{
+		`CREATE TABLE IF NOT EXISTS "sessions_schema_version"`,
+		`CREATE TABLE IF NOT EXISTS "sessions" (`,
+		`INSERT INTO "sessions_schema_version" (version, applied_on) VALUES (1, CURRENT_TIMESTAMP);`,
+	} {
+		if !strings.Contains(ddl, expected) {
+			t.Errorf("Expected %s in %s", expected, ddl)
+		}
+	}
+	if strings.Contains(DDL(MySQL, "sessions"), "TIMESTAMP DEFAULT 0") {
+		t.Errorf("Expected no DEFAULT 0 timestamps for MySQL strict mode")
+	}
+}
+
+func TestDDLFrom(t *testing.T) {
+	ddl := DDLFrom(MySQL, "sessions", 0)
+	for _, expected := range []string{
+		"ALTER TABLE `sessions` MODIFY id VARCHAR(64) NOT NULL",
+		"ALTER TABLE `sessions` ADD INDEX (expires_on)",
+		"ALTER TABLE `sessions` ADD COLUMN user_id VARCHAR(255)",
+		"ALTER TABLE `sessions` ADD INDEX (user_id)",
+		"VALUES (1, CURRENT_TIMESTAMP);",
+	} {
+		if !strings.Contains(ddl, expected) {
+			t.Errorf("Expected %s in %s", expected, ddl)
+		}
+	}
+
+	ddl = DDLFrom(SQLite, "sessions", 1)
+	if strings.Contains(ddl, `CREATE TABLE IF NOT EXISTS "sessions" (`) || strings.Contains(ddl, "VALUES (1,") {
+		t.Errorf("Expected no statements of version 1 in %s", ddl)
+	}
+	if !strings.Contains(ddl, `ALTER TABLE "sessions" ADD COLUMN version`) {
+		t.Errorf("Expected the statements of version 2 in %s", ddl)
+	}
+}
@@ -0,0 +1,215 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Migration is a version of the session table and the statements which
+// bring the previous version to it.
+type Migration struct {
+	Version    int
+	Statements []string
+}
+
+// MigrationLocker is implemented by dialects which can keep several
+// instances from migrating the same table at once. The built-in dialects
+// implement it, Migrate runs unlocked with others.
+type MigrationLocker interface {
+	// LockMigration takes a lock on conn, which Migrate then uses for all
+	// its statements, and returns the function releasing it. unlock is
+	// passed the error of the migration, if any.
+	LockMigration(ctx context.Context, conn *sql.Conn, table string) (unlock func(err error) error, err error)
+}
+
+// execer is a *sql.DB or a *sql.Conn.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// LatestVersion returns the version of the session table this package
+// expects.
+func LatestVersion(dialect Dialect, tableName string) int {
+	migrations := dialect.Migrations(tableName)
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaVersion returns the version of the session table recorded in the
+// <table>_schema_version table, or 0 if it cannot be read.
+func SchemaVersion(db *sql.DB, dialect Dialect, tableName string) int {
+	return schemaVersion(context.Background(), db, dialect, tableName)
+}
+
+func schemaVersion(ctx context.Context, db execer, dialect Dialect, tableName string) int {
+	var version sql.NullInt64
+	err := db.QueryRowContext(ctx, "SELECT MAX(version) FROM "+versionTable(dialect, tableName)).Scan(&version)
+	if err != nil {
+		return 0
+	}
+	return int(version.Int64)
+}
+
+// Migrate creates the session table or brings it to the latest version.
+// Every applied version is recorded in the <table>_schema_version table.
+// Nothing is run when the table is up to date, so a user without DDL
+// privileges can use a table which a DBA created from DDL.
+//
+// Tables created before versions were recorded are adopted as version 1.
+//
+// Instances starting at the same time wait for each other if the dialect
+// is a MigrationLocker.
+func Migrate(db *sql.DB, dialect Dialect, tableName string) error {
+	if SchemaVersion(db, dialect, tableName) >= LatestVersion(dialect, tableName) {
+		return nil
+	}
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	locker, ok := dialect.(MigrationLocker)
+	if !ok {
+		return migrate(ctx, conn, dialect, tableName)
+	}
+	unlock, err := locker.LockMigration(ctx, conn, tableName)
+	if err != nil {
+		return fmt.Errorf("sqlstore: locking %s for migration: %w", tableName, err)
+	}
+	err = migrate(ctx, conn, dialect, tableName)
+	if unlockErr := unlock(err); err == nil {
+		err = unlockErr
+	}
+	return err
+}
+
+// migrate brings the table from the version recorded now to the latest.
+func migrate(ctx context.Context, db execer, dialect Dialect, tableName string) error {
+	version := schemaVersion(ctx, db, dialect, tableName)
+	if version >= LatestVersion(dialect, tableName) {
+		// Migrated by another instance while this one waited.
+		return nil
+	}
+	if _, err := db.ExecContext(ctx, versionTableDDL(dialect, tableName)); err != nil {
+		return err
+	}
+	t := dialect.Quote(tableName)
+	if version == 0 {
+		if _, err := db.ExecContext(ctx, "SELECT id FROM "+t+" WHERE 1 = 0"); err == nil {
+			if err = adoptLegacy(ctx, db, dialect, tableName); err != nil {
+				return err
+			}
+		}
+	}
+	for _, m := range dialect.Migrations(tableName) {
+		if m.Version <= version {
+			continue
+		}
+		for _, q := range m.Statements {
+			if _, err := db.ExecContext(ctx, q); err != nil {
+				return fmt.Errorf("sqlstore: migrating %s to version %d: %w", tableName, m.Version, err)
+			}
+		}
+		_, err := db.ExecContext(ctx, recordVersion(dialect, tableName, m.Version))
+		// Another instance may have migrated the table at the same time.
+		if err != nil && !dialect.IsDuplicateKey(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// DDL returns the statements Migrate runs on an empty database, separated
+// by semicolons, for DBAs who manage the schema by hand. The store is then
+// created with OpenSQLStore.
+func DDL(dialect Dialect, tableName string) string {
+	return ddl(dialect, tableName, 0, nil)
+}
+
+// DDLFrom is like DDL for an existing table of the given version, see
+// SchemaVersion. Version 0 is a table created before versions were
+// recorded, its upgrade is included; the statements adding a column or
+// index which such a table already has fail and can be skipped.
+func DDLFrom(dialect Dialect, tableName string, version int) string {
+	var legacy []string
+	if version == 0 {
+		legacy = dialect.Legacy(tableName)
+		if d, ok := dialect.(mysqlDialect); ok {
+			legacy = append(legacy, d.expiresIndexDDL(tableName))
+		}
+		legacy = append(legacy, userColumnDDL(dialect, tableName)...)
+	}
+	return ddl(dialect, tableName, version, legacy)
+}
+
+func ddl(dialect Dialect, tableName string, version int, legacy []string) string {
+	stmts := append([]string{versionTableDDL(dialect, tableName)}, legacy...)
+	for _, m := range dialect.Migrations(tableName) {
+		if m.Version <= version {
+			continue
+		}
+		stmts = append(stmts, m.Statements...)
+		stmts = append(stmts, recordVersion(dialect, tableName, m.Version))
+	}
+	return strings.Join(stmts, ";\n") + ";\n"
+}
+
+func versionTable(dialect Dialect, tableName string) string {
+	return dialect.Quote(strings.Trim(tableName, "`\"") + "_schema_version")
+}
+
+func versionTableDDL(dialect Dialect, tableName string) string {
+	return "CREATE TABLE IF NOT EXISTS " + versionTable(dialect, tableName) +
+		" (version INTEGER NOT NULL PRIMARY KEY, applied_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+}
+
+func recordVersion(dialect Dialect, tableName string, version int) string {
+	return "INSERT INTO " + versionTable(dialect, tableName) + " (version, applied_on) VALUES (" +
+		strconv.Itoa(version) + ", CURRENT_TIMESTAMP)"
+}
+
+// adoptLegacy brings a table created before versions were recorded to
+// version 1.
+func adoptLegacy(ctx context.Context, db execer, dialect Dialect, tableName string) error {
+	for _, q := range dialect.Legacy(tableName) {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			return fmt.Errorf("sqlstore: upgrading %s: %w", tableName, err)
+		}
+	}
+	if d, ok := dialect.(mysqlDialect); ok {
+		if err := d.addExpiresIndex(ctx, db, tableName); err != nil {
+			return fmt.Errorf("sqlstore: upgrading %s: %w", tableName, err)
+		}
+	}
+	return addUserColumn(ctx, db, dialect, tableName)
+}
+
+// addUserColumn adds the user_id column to tables created before sessions
+// were bound to users. Its index is created by version 1.
+func addUserColumn(ctx context.Context, db execer, dialect Dialect, tableName string) error {
+	t := dialect.Quote(tableName)
+	if _, err := db.ExecContext(ctx, "SELECT user_id FROM "+t+" WHERE 1 = 0"); err == nil {
+		return nil
+	}
+	for _, q := range userColumnDDL(dialect, tableName) {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func userColumnDDL(dialect Dialect, tableName string) []string {
+	t := dialect.Quote(tableName)
+	stmts := []string{"ALTER TABLE " + t + " ADD COLUMN user_id VARCHAR(255)"}
+	if _, ok := dialect.(mysqlDialect); ok {
+		// MySQL has no CREATE INDEX IF NOT EXISTS, so version 1 only
+		// creates the index with the table.
+		stmts = append(stmts, "ALTER TABLE "+t+" ADD INDEX (user_id)")
+	}
+	return stmts
+}
@@ -0,0 +1,40 @@
+package sqlstore
+
+import (
+	"context"
+	"github.com/kimiazhu/golib/sessions/core"
+	"time"
+)
+
+// Move inserts rec and deletes or expires oldID in one transaction.
+func (b *sqlBackend) Move(ctx context.Context, oldID string, rec *core.Record, expireOld time.Time) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.StmtContext(ctx, b.stmtInsert).ExecContext(ctx, values(rec)...)
+	if err != nil {
+		if b.dialect.IsDuplicateKey(err) {
+			return core.ErrDuplicateID
+		}
+		return err
+	}
+	if expireOld.IsZero() {
+		_, err = tx.StmtContext(ctx, b.stmtDelete).ExecContext(ctx, oldID)
+	} else {
+		_, err = tx.StmtContext(ctx, b.stmtExpire).ExecContext(ctx, expireOld, core.Superseded, oldID)
+	}
+	if err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	b.uncached(oldID)
+	if b.cache != nil {
+		b.cache.add(rec)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"github.com/kimiazhu/golib/sessions/core"
+	"sync/atomic"
+)
+
+// SetReplicas makes the store load sessions from the given read replicas
+// of the table, taking turns, while everything else goes to the primary.
+// A session which is missing on the replica, e.g. because it was created
+// a moment ago and has not been replicated yet, or which cannot be read
+// from it is loaded from the primary instead.
+//
+// Replicas may return the previous version of a session which was just
+// saved. Use FailOnConflict or MergeOnConflict if such a session must not
+// overwrite the newer one, conflicts are resolved with the primary.
+//
+// SetReplicas must be called before the store is used. Close closes the
+// replicas as well.
+func (s *SQLStore) SetReplicas(replicas ...*sql.DB) error {
+	b := s.backend
+	selQ := selectColumns + b.table + " WHERE id = " + b.dialect.Placeholder(1)
+	stmts := make([]*sql.Stmt, 0, len(replicas))
+	for _, db := range replicas {
+		stmt, err := db.Prepare(selQ)
+		if err != nil {
+			for _, prepared := range stmts {
+				prepared.Close()
+			}
+			return err
+		}
+		stmts = append(stmts, stmt)
+	}
+	b.replicas, b.replicaDBs = stmts, replicas
+	return nil
+}
+
+// selectRecord reads the row of id from a replica, or from the primary.
+func (b *sqlBackend) selectRecord(ctx context.Context, id string) (*core.Record, error) {
+	if n := len(b.replicas); n > 0 {
+		stmt := b.replicas[atomic.AddUint32(&b.nextReplica, 1)%uint32(n)]
+		rec, err := scanRecord(stmt.QueryRowContext(ctx, id))
+		if err == nil {
+			return rec, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		if err != sql.ErrNoRows {
+			b.reportFailure("load session from replica", err)
+		}
+	}
+	return scanRecord(b.stmtSelect.QueryRowContext(ctx, id))
+}
+
+// LoadPrimary reads the row of id from the primary, bypassing the cache and
+// the replicas.
+func (b *sqlBackend) LoadPrimary(ctx context.Context, id string) (*core.Record, error) {
+	rec, err := scanRecord(b.stmtSelect.QueryRowContext(ctx, id))
+	if err == sql.ErrNoRows {
+		return nil, core.ErrNotFound
+	}
+	return rec, err
+}
+
+func (b *sqlBackend) closeReplicas() {
+	for _, stmt := range b.replicas {
+		stmt.Close()
+	}
+	for _, db := range b.replicaDBs {
+		db.Close()
+	}
+}
@@ -0,0 +1,403 @@
+// Package sqlstore is a Gorilla Sessions backend for SQL databases.
+//
+// Sessions are stored in a table of any database/sql database. The
+// differences between databases are handled by a Dialect, this package
+// ships MySQL, Postgres and SQLite.
+//
+// It is derived from mysqlstore, see sessions/mysqlstore/LICENSE and
+// sessions/mysqlstore/CONTRIBUTORS.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/kimiazhu/golib/sessions/core"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SQLStore struct {
+	*core.Store
+	backend *sqlBackend
+
+	cleanupMu    sync.Mutex
+	cleanupBatch int
+	cleanupQuit  chan struct{}
+	cleanupDone  chan struct{}
+}
+
+// sqlBackend implements core.Backend with a table.
+type sqlBackend struct {
+	db         *sql.DB
+	dialect    Dialect
+	table      string
+	stmtInsert *sql.Stmt
+	stmtDelete *sql.Stmt
+	stmtUpdate *sql.Stmt
+	stmtSelect *sql.Stmt
+
+	stmtDeleteExpired *sql.Stmt
+	stmtCount         *sql.Stmt
+	stmtList          *sql.Stmt
+	stmtListByUser    *sql.Stmt
+	stmtListIDsByUser *sql.Stmt
+	stmtDeleteByUser  *sql.Stmt
+	stmtExpire        *sql.Stmt
+	stmtSwap          *sql.Stmt
+	stmtTouch         *sql.Stmt
+
+	cache    *rowCache
+	notifier Notifier
+
+	replicas    []*sql.Stmt
+	replicaDBs  []*sql.DB
+	nextReplica uint32
+
+	// reportFailure is core.Store.ReportFailure.
+	reportFailure func(op string, err error)
+}
+
+var columns = []string{"id", "session_data", "user_id", "created_on", "modified_on", "expires_on", "version"}
+
+var selectColumns = "SELECT " + strings.Join(columns, ", ") + " FROM "
+
+func NewSQLStore(driverName, dataSourceName string, dialect Dialect, tableName string, path string, maxAge int, keyPairs ...[]byte) (*SQLStore, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSQLStoreFromConnection(db, dialect, tableName, path, maxAge, keyPairs...)
+}
+
+// NewSQLStoreFromConnection creates the session table or migrates it to
+// the latest version, see Migrate, and returns a store using it.
+func NewSQLStoreFromConnection(db *sql.DB, dialect Dialect, tableName string, path string, maxAge int, keyPairs ...[]byte) (*SQLStore, error) {
+	if err := Migrate(db, dialect, tableName); err != nil {
+		if dialect.IsPermissionDenied(err) {
+			return nil, fmt.Errorf("sqlstore: not allowed to migrate %s, apply DDL() and use OpenSQLStore: %w", tableName, err)
+		}
+		return nil, err
+	}
+	return OpenSQLStore(db, dialect, tableName, path, maxAge, keyPairs...)
+}
+
+// OpenSQLStore returns a store using an existing table without running any
+// DDL. The table must be at the latest version, e.g. created from DDL.
+func OpenSQLStore(db *sql.DB, dialect Dialect, tableName string, path string, maxAge int, keyPairs ...[]byte) (*SQLStore, error) {
+	t := dialect.Quote(tableName)
+	where := " WHERE id = " + dialect.Placeholder(1)
+
+	insQ := "INSERT INTO " + t + " (" + strings.Join(columns, ", ") + ") VALUES (" +
+		placeholders(dialect, 1, len(columns)) + ")"
+	stmtInsert, stmtErr := db.Prepare(insQ)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+
+	delQ := "DELETE FROM " + t + where
+	stmtDelete, stmtErr := db.Prepare(delQ)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+
+	selQ := selectColumns + t + where
+	stmtSelect, stmtErr := db.Prepare(selQ)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+
+	stmtDeleteExpired, stmtErr := db.Prepare(dialect.DeleteExpired(tableName))
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+
+	cntQ := "SELECT COUNT(*) FROM " + t + " WHERE expires_on >= " + dialect.Placeholder(1)
+	stmtCount, stmtErr := db.Prepare(cntQ)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+
+	lstQ := selectColumns + t +
+		" WHERE id > " + dialect.Placeholder(1) + " ORDER BY id LIMIT " + dialect.Placeholder(2)
+	stmtList, stmtErr := db.Prepare(lstQ)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+
+	usrQ := selectColumns + t + " WHERE user_id = " + dialect.Placeholder(1) + " ORDER BY created_on, id"
+	stmtListByUser, stmtErr := db.Prepare(usrQ)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+
+	usrIDQ := "SELECT id, created_on FROM " + t + " WHERE user_id = " + dialect.Placeholder(1) +
+		" AND expires_on > " + dialect.Placeholder(2) + " ORDER BY created_on, id"
+	stmtListIDsByUser, stmtErr := db.Prepare(usrIDQ)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+
+	delUsrQ := "DELETE FROM " + t + " WHERE user_id = " + dialect.Placeholder(1)
+	stmtDeleteByUser, stmtErr := db.Prepare(delUsrQ)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+
+	expQ := "UPDATE " + t + " SET expires_on = " + dialect.Placeholder(1) + ", version = " + dialect.Placeholder(2) + " WHERE id = " + dialect.Placeholder(3)
+	stmtExpire, stmtErr := db.Prepare(expQ)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+
+	set := make([]string, len(columns)-1)
+	for i, c := range columns[1:] {
+		set[i] = c + " = " + dialect.Placeholder(i+1)
+	}
+	n := len(columns)
+	updQ := "UPDATE " + t + " SET " + strings.Join(set, ", ") + " WHERE id = " + dialect.Placeholder(n)
+	stmtUpdate, stmtErr := db.Prepare(updQ)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+
+	swpQ := updQ + " AND version = " + dialect.Placeholder(n+1)
+	stmtSwap, stmtErr := db.Prepare(swpQ)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+
+	tchQ := "UPDATE " + t + " SET modified_on = " + dialect.Placeholder(1) +
+		", expires_on = " + dialect.Placeholder(2) + " WHERE id = " + dialect.Placeholder(3)
+	stmtTouch, stmtErr := db.Prepare(tchQ)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+
+	backend := &sqlBackend{
+		db:                db,
+		dialect:           dialect,
+		table:             t,
+		stmtInsert:        stmtInsert,
+		stmtDelete:        stmtDelete,
+		stmtUpdate:        stmtUpdate,
+		stmtSelect:        stmtSelect,
+		stmtDeleteExpired: stmtDeleteExpired,
+		stmtCount:         stmtCount,
+		stmtList:          stmtList,
+		stmtListByUser:    stmtListByUser,
+		stmtListIDsByUser: stmtListIDsByUser,
+		stmtDeleteByUser:  stmtDeleteByUser,
+		stmtExpire:        stmtExpire,
+		stmtSwap:          stmtSwap,
+		stmtTouch:         stmtTouch,
+	}
+	store := core.NewStore(backend, path, maxAge, keyPairs...)
+	backend.reportFailure = store.ReportFailure
+	return &SQLStore{
+		Store:   store,
+		backend: backend,
+	}, nil
+}
+
+func (s *SQLStore) Close() {
+	s.StopCleanup()
+	b := s.backend
+	b.closeReplicas()
+	b.stmtTouch.Close()
+	b.stmtSwap.Close()
+	b.stmtExpire.Close()
+	b.stmtDeleteByUser.Close()
+	b.stmtListByUser.Close()
+	b.stmtListIDsByUser.Close()
+	b.stmtList.Close()
+	b.stmtCount.Close()
+	b.stmtDeleteExpired.Close()
+	b.stmtSelect.Close()
+	b.stmtUpdate.Close()
+	b.stmtDelete.Close()
+	b.stmtInsert.Close()
+	b.db.Close()
+}
+
+// Load returns the row of id from the cache, or from the table when it is
+// not cached, see SetReplicas.
+func (b *sqlBackend) Load(ctx context.Context, id string) (*core.Record, error) {
+	var gen uint64
+	if b.cache != nil {
+		if rec, ok := b.cache.get(id); ok {
+			return rec, nil
+		}
+		gen = b.cache.generation(id)
+	}
+	rec, scanErr := b.selectRecord(ctx, id)
+	if scanErr == sql.ErrNoRows {
+		return nil, core.ErrNotFound
+	} else if scanErr != nil {
+		return nil, scanErr
+	}
+	if b.cache != nil {
+		b.cache.addLoaded(rec, gen)
+	}
+	return rec, nil
+}
+
+func (b *sqlBackend) Insert(ctx context.Context, rec *core.Record) error {
+	_, insErr := b.stmtInsert.ExecContext(ctx, values(rec)...)
+	if insErr != nil {
+		if b.dialect.IsDuplicateKey(insErr) {
+			return core.ErrDuplicateID
+		}
+		return insErr
+	}
+	if b.cache != nil {
+		b.cache.add(rec)
+	}
+	return nil
+}
+
+// Save updates the row of rec. It does not insert a missing row, see
+// core.Backend.
+func (b *sqlBackend) Save(ctx context.Context, rec *core.Record) error {
+	args := append(values(rec)[1:], rec.ID)
+	res, err := b.stmtUpdate.ExecContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		b.uncached(rec.ID)
+		if err = b.exists(ctx, rec.ID); err != nil {
+			return err
+		}
+	}
+	b.cached(rec)
+	return nil
+}
+
+// exists returns ErrNotFound if there is no row of id. An update which
+// affected no rows may have found one: MySQL does not count rows which
+// already had the values.
+func (b *sqlBackend) exists(ctx context.Context, id string) error {
+	_, err := scanRecord(b.stmtSelect.QueryRowContext(ctx, id))
+	if err == sql.ErrNoRows {
+		return core.ErrNotFound
+	}
+	return err
+}
+
+func (b *sqlBackend) Delete(ctx context.Context, id string) error {
+	_, delErr := b.stmtDelete.ExecContext(ctx, id)
+	if delErr != nil {
+		return delErr
+	}
+	b.uncached(id)
+	return nil
+}
+
+// Swap updates the row of rec if it still has version.
+func (b *sqlBackend) Swap(ctx context.Context, rec *core.Record, version int64) error {
+	args := append(values(rec)[1:], rec.ID, version)
+	res, err := b.stmtSwap.ExecContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// The cached row, if any, is outdated.
+		b.uncached(rec.ID)
+		_, err = scanRecord(b.stmtSelect.QueryRowContext(ctx, rec.ID))
+		if err == sql.ErrNoRows {
+			return core.ErrNotFound
+		} else if err != nil {
+			return err
+		}
+		return core.ErrVersionConflict
+	}
+	b.cached(rec)
+	return nil
+}
+
+// Touch updates the times of a row without writing its data.
+func (b *sqlBackend) Touch(ctx context.Context, id string, modifiedOn, expiresOn time.Time) error {
+	res, err := b.stmtTouch.ExecContext(ctx, modifiedOn, expiresOn, id)
+	if err != nil {
+		return err
+	}
+	b.uncached(id)
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return b.exists(ctx, id)
+	}
+	return nil
+}
+
+func (b *sqlBackend) Count(ctx context.Context) (int64, error) {
+	var n int64
+	err := b.stmtCount.QueryRowContext(ctx, time.Now()).Scan(&n)
+	return n, err
+}
+
+// List pages through the table ordered by id, the cursor is the last id
+// of the previous page.
+func (b *sqlBackend) List(ctx context.Context, cursor string, limit int) ([]*core.Record, string, error) {
+	rows, err := b.stmtList.QueryContext(ctx, cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	recs, err := scanRecords(rows)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(recs) < limit {
+		return recs, "", nil
+	}
+	return recs, recs[len(recs)-1].ID, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRecord(row scanner) (*core.Record, error) {
+	rec := &core.Record{}
+	var userID sql.NullString
+	err := row.Scan(&rec.ID, &rec.Data, &userID, &rec.CreatedOn, &rec.ModifiedOn, &rec.ExpiresOn, &rec.Version)
+	if err != nil {
+		return nil, err
+	}
+	rec.UserID = userID.String
+	return rec, nil
+}
+
+func scanRecords(rows *sql.Rows) ([]*core.Record, error) {
+	defer rows.Close()
+	var recs []*core.Record
+	for rows.Next() {
+		rec, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, rows.Err()
+}
+
+// values returns the columns of rec in the order of columns.
+func values(rec *core.Record) []interface{} {
+	return []interface{}{rec.ID, rec.Data, nullString(rec.UserID), rec.CreatedOn, rec.ModifiedOn, rec.ExpiresOn, rec.Version}
+}
+
+// nullString stores an empty string as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
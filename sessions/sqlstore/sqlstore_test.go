@@ -0,0 +1,257 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"github.com/gorilla/sessions"
+	"github.com/kimiazhu/golib/sessions/core"
+	"github.com/kimiazhu/golib/sessions/storetest"
+	_ "modernc.org/sqlite"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openSQLite returns a SQLite database in a temporary directory.
+func openSQLite(t *testing.T) *sql.DB {
+	dsn := "file:" + filepath.Join(t.TempDir(), "sessions.db") + "?_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("Error opening SQLite: %v", err)
+	}
+	return db
+}
+
+func TestSQLStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, maxAge int) sessions.Store {
+		store, err := NewSQLStoreFromConnection(openSQLite(t), SQLite, "sessionstore", "/", maxAge, []byte("secret-key"))
+		if err != nil {
+			t.Fatalf("Error creating store: %v", err)
+		}
+		t.Cleanup(store.Close)
+		return store
+	})
+}
+
+func TestSQLStoreCachedConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, maxAge int) sessions.Store {
+		store, err := NewSQLStoreFromConnection(openSQLite(t), SQLite, "sessionstore", "/", maxAge, []byte("secret-key"))
+		if err != nil {
+			t.Fatalf("Error creating store: %v", err)
+		}
+		t.Cleanup(store.Close)
+		store.SetCache(100, 0, nil)
+		return store
+	})
+}
+
+func TestSQLStoreConflicts(t *testing.T) {
+	store, err := NewSQLStoreFromConnection(openSQLite(t), SQLite, "sessionstore", "/", 3600, []byte("secret-key"))
+	if err != nil {
+		t.Fatalf("Error creating store: %v", err)
+	}
+	defer store.Close()
+	store.SetCache(100, 0, nil)
+	storetest.RunConflicts(t, store.Store)
+}
+
+func TestSQLStoreReplicas(t *testing.T) {
+	store, err := NewSQLStoreFromConnection(openSQLite(t), SQLite, "sessionstore", "/", 3600, []byte("secret-key"))
+	if err != nil {
+		t.Fatalf("Error creating store: %v", err)
+	}
+	defer store.Close()
+	replicaDB := openSQLite(t)
+	if err = Migrate(replicaDB, SQLite, "sessionstore"); err != nil {
+		t.Fatalf("Error creating replica: %v", err)
+	}
+	replica, err := OpenSQLStore(replicaDB, SQLite, "sessionstore", "/", 3600, []byte("secret-key"))
+	if err != nil {
+		t.Fatalf("Error creating replica: %v", err)
+	}
+	if err = store.SetReplicas(replicaDB); err != nil {
+		t.Fatalf("Error setting replicas: %v", err)
+	}
+
+	// The session was not replicated yet: it is loaded from the primary.
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	session, _ := store.New(req, "session-key")
+	session.Values["foo"] = "first"
+	if err = store.Save(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	loaded, err := store.Lookup(context.Background(), "session-key", session.ID)
+	if err != nil || loaded.Values["foo"] != "first" {
+		t.Fatalf("Expected the session from the primary; Got %v, %v", loaded, err)
+	}
+
+	// Once replicated, it is loaded from the replica, even if it is stale.
+	rec, _ := store.backend.Load(context.Background(), session.ID)
+	if err = replica.backend.Insert(context.Background(), rec); err != nil {
+		t.Fatalf("Error replicating session: %v", err)
+	}
+	session.Values["foo"] = "second"
+	if err = store.Save(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	loaded, err = store.Lookup(context.Background(), "session-key", session.ID)
+	if err != nil || loaded.Values["foo"] != "first" {
+		t.Errorf("Expected the session from the replica; Got %v, %v", loaded.Values, err)
+	}
+
+	// Saving the stale session conflicts, and is merged with the primary.
+	store.OnConflict = core.MergeOnConflict
+	store.Merge = func(stored, session *sessions.Session) error {
+		session.Values["foo"] = stored.Values["foo"]
+		return nil
+	}
+	loaded.Values["bar"] = "baz"
+	if err = store.Save(req, httptest.NewRecorder(), loaded); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	store.backend.replicas = nil
+	loaded, err = store.Lookup(context.Background(), "session-key", session.ID)
+	if err != nil || loaded.Values["foo"] != "second" || loaded.Values["bar"] != "baz" {
+		t.Errorf("Expected foo=second and bar=baz; Got %v, %v", loaded.Values, err)
+	}
+}
+
+func TestSQLStoreRegenerateGrace(t *testing.T) {
+	store, err := NewSQLStoreFromConnection(openSQLite(t), SQLite, "sessionstore", "/", 3600, []byte("secret-key"))
+	if err != nil {
+		t.Fatalf("Error creating store: %v", err)
+	}
+	defer store.Close()
+	store.RegenerateGrace = time.Minute
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	session, _ := store.New(req, "session-key")
+	session.Values["foo"] = "bar"
+	if err = store.Save(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	oldID := session.ID
+	if err = store.Regenerate(req, httptest.NewRecorder(), session); err != nil {
+		t.Fatalf("Error regenerating session: %v", err)
+	}
+	old, err := store.Lookup(context.Background(), "session-key", oldID)
+	if err != nil {
+		t.Fatalf("Error looking up the old ID: %v", err)
+	}
+	old.Values["foo"] = "baz"
+	if err = store.Save(req, httptest.NewRecorder(), old); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	rec, err := store.backend.Load(context.Background(), oldID)
+	if err != nil {
+		t.Fatalf("Error loading the old ID: %v", err)
+	}
+	if rec.Version != core.Superseded || time.Until(rec.ExpiresOn) > time.Minute {
+		t.Errorf("Expected the old ID to be superseded within a minute; Got version %d, expiring on %v", rec.Version, rec.ExpiresOn)
+	}
+}
+
+func TestSQLStoreUserSessions(t *testing.T) {
+	store, err := NewSQLStoreFromConnection(openSQLite(t), SQLite, "sessionstore", "/", 3600, []byte("secret-key"))
+	if err != nil {
+		t.Fatalf("Error creating store: %v", err)
+	}
+	defer store.Close()
+	store.UserKey = "user_id"
+	if err = store.SetMaxSessionsPerUser(2); err != nil {
+		t.Fatalf("Error limiting user sessions: %v", err)
+	}
+	ctx := context.Background()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		session, _ := store.New(req, "session-key")
+		session.Values["user_id"] = "alice"
+		if err = store.Save(req, httptest.NewRecorder(), session); err != nil {
+			t.Fatalf("Error saving session: %v", err)
+		}
+		ids = append(ids, session.ID)
+	}
+	recs, err := store.backend.ListIDsByUser(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Error listing user sessions: %v", err)
+	}
+	if len(recs) != 2 || recs[0].ID != ids[1] || recs[1].ID != ids[2] {
+		t.Errorf("Expected the 2 newest sessions; Got %d", len(recs))
+	}
+	if _, err = store.Lookup(ctx, "session-key", ids[0]); err != core.ErrNotFound {
+		t.Errorf("Expected the oldest session to be evicted; Got %v", err)
+	}
+}
+
+func TestSQLStoreTouch(t *testing.T) {
+	store, err := NewSQLStoreFromConnection(openSQLite(t), SQLite, "sessionstore", "/", 3600, []byte("secret-key"))
+	if err != nil {
+		t.Fatalf("Error creating store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	now := time.Now()
+	rec := &core.Record{ID: "touched", CreatedOn: now, ModifiedOn: now, ExpiresOn: now.Add(time.Hour), Version: 1}
+	if err = store.backend.Insert(ctx, rec); err != nil {
+		t.Fatalf("Error inserting record: %v", err)
+	}
+	// Touching twice with the same times changes nothing the second time.
+	for i := 0; i < 2; i++ {
+		if err = store.backend.Touch(ctx, rec.ID, now, now.Add(2*time.Hour)); err != nil {
+			t.Fatalf("Error touching record: %v", err)
+		}
+	}
+	if err = store.backend.Touch(ctx, "missing", now, now.Add(2*time.Hour)); err != core.ErrNotFound {
+		t.Errorf("Expected ErrNotFound; Got %v", err)
+	}
+}
+
+func TestMigrateRollback(t *testing.T) {
+	db := openSQLite(t)
+	defer db.Close()
+	// A legacy table which already has the column of version 2.
+	_, err := db.Exec(`CREATE TABLE "sessionstore" (id TEXT NOT NULL PRIMARY KEY, session_data BLOB, ` +
+		`created_on TIMESTAMP NOT NULL, modified_on TIMESTAMP NOT NULL, expires_on TIMESTAMP NOT NULL, version INTEGER)`)
+	if err != nil {
+		t.Fatalf("Error creating table: %v", err)
+	}
+	if err = Migrate(db, SQLite, "sessionstore"); err == nil {
+		t.Fatalf("Expected version 2 to fail")
+	}
+	if v := SchemaVersion(db, SQLite, "sessionstore"); v != 0 {
+		t.Errorf("Expected version 1 to be rolled back; Got version %d", v)
+	}
+	if _, err = db.Exec(`SELECT user_id FROM "sessionstore"`); err == nil {
+		t.Errorf("Expected the user_id column to be rolled back")
+	}
+}
+
+func TestMigrateConcurrently(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "sessions.db") + "?_pragma=busy_timeout(5000)"
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		// Every instance has its own connections, like separate processes.
+		db, err := sql.Open("sqlite", dsn)
+		if err != nil {
+			t.Fatalf("Error opening SQLite: %v", err)
+		}
+		defer db.Close()
+		go func() { errs <- Migrate(db, SQLite, "sessionstore") }()
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Error migrating: %v", err)
+		}
+	}
+
+	db, _ := sql.Open("sqlite", dsn)
+	defer db.Close()
+	if v := SchemaVersion(db, SQLite, "sessionstore"); v != LatestVersion(SQLite, "sessionstore") {
+		t.Errorf("Expected version %d; Got %d", LatestVersion(SQLite, "sessionstore"), v)
+	}
+}
@@ -0,0 +1,54 @@
+package sqlstore
+
+import (
+	"context"
+	"github.com/kimiazhu/golib/sessions/core"
+	"time"
+)
+
+// ListByUser returns the rows of userID ordered by created_on.
+func (b *sqlBackend) ListByUser(ctx context.Context, userID string) ([]*core.Record, error) {
+	rows, err := b.stmtListByUser.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanRecords(rows)
+}
+
+// ListIDsByUser returns the IDs and creation times of the unexpired rows
+// of userID ordered by created_on, without reading their data.
+func (b *sqlBackend) ListIDsByUser(ctx context.Context, userID string) ([]*core.Record, error) {
+	rows, err := b.stmtListIDsByUser.QueryContext(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var recs []*core.Record
+	for rows.Next() {
+		rec := &core.Record{}
+		if err = rows.Scan(&rec.ID, &rec.CreatedOn); err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, rows.Err()
+}
+
+func (b *sqlBackend) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	if b.cache != nil {
+		recs, err := b.ListByUser(ctx, userID)
+		if err != nil {
+			return 0, err
+		}
+		defer func() {
+			for _, rec := range recs {
+				b.uncached(rec.ID)
+			}
+		}()
+	}
+	res, err := b.stmtDeleteByUser.ExecContext(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
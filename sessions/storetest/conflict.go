@@ -0,0 +1,79 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"github.com/gorilla/sessions"
+	"github.com/kimiazhu/golib/sessions/core"
+	"net/http/httptest"
+	"testing"
+)
+
+// RunConflicts tests the conflict strategies of a store built on
+// core.Store. It changes store.OnConflict and store.Merge.
+func RunConflicts(t *testing.T, store *core.Store) {
+	session := get(t, store, "")
+	cookie := save(t, store, session)
+
+	// Two requests load the session and save it in turn.
+	race := func(first, second string) (error, error) {
+		a, b := get(t, store, cookie), get(t, store, cookie)
+		a.Values[first] = true
+		b.Values[second] = true
+		return store.Save(request(cookie), httptest.NewRecorder(), a),
+			store.Save(request(cookie), httptest.NewRecorder(), b)
+	}
+
+	store.OnConflict = core.FailOnConflict
+	errA, errB := race("a", "b")
+	if errA != nil {
+		t.Fatalf("Error saving session: %v", errA)
+	}
+	var conflict *core.ConflictError
+	if !errors.As(errB, &conflict) || !errors.Is(errB, core.ErrVersionConflict) {
+		t.Errorf("Expected a ConflictError; Got %v", errB)
+	}
+
+	store.OnConflict = core.MergeOnConflict
+	store.Merge = func(stored, session *sessions.Session) error {
+		for k, v := range stored.Values {
+			if _, ok := session.Values[k]; !ok {
+				session.Values[k] = v
+			}
+		}
+		return nil
+	}
+	if errA, errB = race("c", "d"); errA != nil || errB != nil {
+		t.Fatalf("Error saving session: %v, %v", errA, errB)
+	}
+	values := get(t, store, cookie).Values
+	if values["a"] != true || values["c"] != true || values["d"] != true {
+		t.Errorf("Expected a, c and d to be merged; Got %v", values)
+	}
+
+	store.OnConflict = core.LastWriteWins
+	if errA, errB = race("e", "f"); errA != nil || errB != nil {
+		t.Fatalf("Error saving session: %v, %v", errA, errB)
+	}
+	values = get(t, store, cookie).Values
+	if values["e"] != nil || values["f"] != true {
+		t.Errorf("Expected only f to be saved; Got %v", values)
+	}
+
+	// A session revoked while a request uses it stays revoked.
+	for _, strategy := range []core.ConflictStrategy{core.LastWriteWins, core.FailOnConflict, core.MergeOnConflict} {
+		store.OnConflict = strategy
+		session = get(t, store, cookie)
+		session.Values["g"] = true
+		if err := store.Revoke(context.Background(), session.ID); err != nil {
+			t.Fatalf("Error revoking session: %v", err)
+		}
+		if err := store.Save(request(cookie), httptest.NewRecorder(), session); err != nil {
+			t.Fatalf("%d: Error saving revoked session: %v", strategy, err)
+		}
+		if session = get(t, store, cookie); !session.IsNew {
+			t.Errorf("%d: Expected the revoked session to stay deleted; Got %v", strategy, session.Values)
+		}
+		cookie = save(t, store, session)
+	}
+}
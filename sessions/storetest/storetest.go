@@ -0,0 +1,266 @@
+// Package storetest is a conformance test suite for sessions.Store
+// implementations. Run it from a test of the store:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T, maxAge int) sessions.Store {
+//			return memstore.NewMemStore("/", maxAge, []byte("secret-key"))
+//		})
+//	}
+package storetest
+
+import (
+	"encoding/gob"
+	"fmt"
+	"github.com/gorilla/sessions"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Name is the session name used by the suite.
+const Name = "session-key"
+
+// FlashMessage is a custom type stored by the suite, it is registered with
+// gob.
+type FlashMessage struct {
+	Type    int
+	Message string
+}
+
+func init() {
+	gob.Register(FlashMessage{})
+}
+
+// NewStore returns a store whose sessions and cookies expire after maxAge
+// seconds. Every call of one test may share the same backend.
+type NewStore func(t *testing.T, maxAge int) sessions.Store
+
+// Run runs the suite against the stores returned by newStore. The expiry
+// test waits a second and is skipped with -short.
+func Run(t *testing.T, newStore NewStore) {
+	t.Run("Flashes", func(t *testing.T) { testFlashes(t, newStore(t, 3600)) })
+	t.Run("Values", func(t *testing.T) { testValues(t, newStore(t, 3600)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newStore(t, 3600)) })
+	t.Run("Expiry", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("skipping expiry in short mode")
+		}
+		testExpiry(t, newStore(t, 1))
+	})
+	t.Run("ConcurrentSaves", func(t *testing.T) { testConcurrentSaves(t, newStore(t, 3600)) })
+	t.Run("ConcurrentNew", func(t *testing.T) { testConcurrentNew(t, newStore(t, 3600)) })
+	t.Run("Tampering", func(t *testing.T) { testTampering(t, newStore(t, 3600)) })
+}
+
+// request returns a request carrying cookie, if it is not empty.
+func request(cookie string) *http.Request {
+	req := httptest.NewRequest("GET", "http://localhost:8080/", nil)
+	if cookie != "" {
+		req.Header.Add("Cookie", cookie)
+	}
+	return req
+}
+
+// get loads the session of cookie.
+func get(t *testing.T, store sessions.Store, cookie string) *sessions.Session {
+	session, err := store.New(request(cookie), Name)
+	if err != nil {
+		t.Fatalf("Error getting session: %v", err)
+	}
+	return session
+}
+
+// save saves session and returns the cookie to send with the next
+// request.
+func save(t *testing.T, store sessions.Store, session *sessions.Session) string {
+	rsp := httptest.NewRecorder()
+	if err := store.Save(request(""), rsp, session); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	cookies := rsp.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expected 1 cookie; Got %v", rsp.Header())
+	}
+	return cookies[0].Name + "=" + cookies[0].Value
+}
+
+func testFlashes(t *testing.T, store sessions.Store) {
+	session := get(t, store, "")
+	if flashes := session.Flashes(); len(flashes) != 0 {
+		t.Errorf("Expected empty flashes; Got %v", flashes)
+	}
+	session.AddFlash("foo")
+	session.AddFlash("bar")
+	session.AddFlash("baz", "custom_key")
+	cookie := save(t, store, session)
+
+	session = get(t, store, cookie)
+	if flashes := session.Flashes(); len(flashes) != 2 || flashes[0] != "foo" || flashes[1] != "bar" {
+		t.Errorf("Expected foo,bar; Got %v", flashes)
+	}
+	if flashes := session.Flashes("custom_key"); len(flashes) != 1 || flashes[0] != "baz" {
+		t.Errorf("Expected baz; Got %v", flashes)
+	}
+	cookie = save(t, store, session)
+
+	session = get(t, store, cookie)
+	if flashes := session.Flashes(); len(flashes) != 0 {
+		t.Errorf("Expected dumped flashes; Got %v", flashes)
+	}
+}
+
+func testValues(t *testing.T, store sessions.Store) {
+	session := get(t, store, "")
+	if !session.IsNew {
+		t.Errorf("Expected a new session")
+	}
+	session.Values["name"] = "alice"
+	session.Values[42] = 3.14
+	session.AddFlash(FlashMessage{42, "foo"})
+	cookie := save(t, store, session)
+
+	session = get(t, store, cookie)
+	if session.IsNew {
+		t.Errorf("Expected a stored session")
+	}
+	if session.Values["name"] != "alice" || session.Values[42] != 3.14 {
+		t.Errorf("Expected the saved values; Got %v", session.Values)
+	}
+	flashes := session.Flashes()
+	if len(flashes) != 1 || flashes[0] != (FlashMessage{42, "foo"}) {
+		t.Errorf("Expected %#v; Got %#v", FlashMessage{42, "foo"}, flashes)
+	}
+}
+
+func testDelete(t *testing.T, store sessions.Store) {
+	session := get(t, store, "")
+	session.Values["name"] = "alice"
+	cookie := save(t, store, session)
+
+	session = get(t, store, cookie)
+	session.Options.MaxAge = -1
+	rsp := httptest.NewRecorder()
+	if err := store.Save(request(cookie), rsp, session); err != nil {
+		t.Fatalf("Error deleting session: %v", err)
+	}
+	if cookies := rsp.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("Expected a cookie deleting the session; Got %v", rsp.Header())
+	}
+
+	session = get(t, store, cookie)
+	if !session.IsNew || len(session.Values) != 0 {
+		t.Errorf("Expected a new session after delete; Got %v", session.Values)
+	}
+}
+
+func testExpiry(t *testing.T, store sessions.Store) {
+	session := get(t, store, "")
+	session.Values["name"] = "alice"
+	cookie := save(t, store, session)
+
+	time.Sleep(1100 * time.Millisecond)
+	session, _ = store.New(request(cookie), Name)
+	if session == nil || !session.IsNew || len(session.Values) != 0 {
+		t.Errorf("Expected a new session after expiry; Got %v", session)
+	}
+}
+
+func testConcurrentSaves(t *testing.T, store sessions.Store) {
+	session := get(t, store, "")
+	session.Values["n"] = -1
+	cookie := save(t, store, session)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			session, err := store.New(request(cookie), Name)
+			if err != nil {
+				errs <- err
+				return
+			}
+			session.Values["n"] = i
+			if err = store.Save(request(cookie), httptest.NewRecorder(), session); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Error in concurrent save: %v", err)
+	}
+
+	session = get(t, store, cookie)
+	if n, ok := session.Values["n"].(int); session.IsNew || !ok || n < 0 {
+		t.Errorf("Expected the value of one of the saves; Got %v", session.Values)
+	}
+}
+
+func testConcurrentNew(t *testing.T, store sessions.Store) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	cookies := make(map[string]int)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			session, err := store.New(request(""), Name)
+			if err != nil {
+				t.Errorf("Error getting session: %v", err)
+				return
+			}
+			session.Values["n"] = i
+			rsp := httptest.NewRecorder()
+			if err = store.Save(request(""), rsp, session); err != nil {
+				t.Errorf("Error saving session: %v", err)
+				return
+			}
+			c := rsp.Result().Cookies()[0]
+			mu.Lock()
+			cookies[c.Name+"="+c.Value] = i
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+	if len(cookies) != 20 {
+		t.Fatalf("Expected 20 distinct cookies; Got %d", len(cookies))
+	}
+	for cookie, i := range cookies {
+		if n := get(t, store, cookie).Values["n"]; n != i {
+			t.Errorf("Expected %d; Got %v", i, n)
+		}
+	}
+}
+
+func testTampering(t *testing.T, store sessions.Store) {
+	session := get(t, store, "")
+	session.Values["name"] = "alice"
+	cookie := save(t, store, session)
+
+	tampered := []string{
+		cookie[:len(cookie)-2] + flip(cookie[len(cookie)-2]) + cookie[len(cookie)-1:],
+		cookie[:len(Name)+1] + "garbage",
+		Name + "=",
+		fmt.Sprintf("%s=%s", Name, "MTIzfGFiY3xkZWY="),
+	}
+	for _, c := range tampered {
+		session, _ := store.New(request(c), Name)
+		if session == nil {
+			t.Errorf("Expected a new session for %s; Got nil", c)
+		} else if !session.IsNew || session.Values["name"] != nil {
+			t.Errorf("Expected a new session for %s; Got %v", c, session.Values)
+		}
+	}
+}
+
+func flip(c byte) string {
+	if c == 'A' {
+		return "B"
+	}
+	return "A"
+}
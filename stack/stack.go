@@ -17,30 +17,65 @@ var (
 	slash     = []byte("/")
 )
 
+// Frame is a frame of a call stack.
+type Frame struct {
+	PC       uintptr
+	File     string
+	Line     int
+	Function string
+	// Source is the trimmed source line, or "" if the file cannot be read.
+	Source string
+}
+
 func CallStack(skip int) []byte {
+	return Format(frames(skip))
+}
+
+// Frames returns the call stack like CallStack, but parsed into frames.
+func Frames(skip int) []Frame {
+	return frames(skip)
+}
+
+// Format prints frames like CallStack.
+func Format(frames []Frame) []byte {
 	buf := new(bytes.Buffer) // the returned data
+	for _, f := range frames {
+		// Print this much at least.  If we can't find the source, it won't show.
+		fmt.Fprintf(buf, "%s:%d (0x%x)\n", f.File, f.Line, f.PC)
+		if f.Source != "" {
+			fmt.Fprintf(buf, "\t%s: %s\n", f.Function, f.Source)
+		}
+	}
+	return buf.Bytes()
+}
+
+// frames returns the call stack of its caller, which is frame 0, without
+// the first skip frames.
+func frames(skip int) []Frame {
+	var frames []Frame
 	// As we loop, we open files and read them. These variables record the currently
 	// loaded file.
 	var lines [][]byte
 	var lastFile string
-	for i := skip; ; i++ { // Skip the expected number of frames
+	for i := skip + 1; ; i++ { // Skip the expected number of frames
 		pc, file, line, ok := runtime.Caller(i)
 		if !ok {
 			break
 		}
-		// Print this much at least.  If we can't find the source, it won't show.
-		fmt.Fprintf(buf, "%s:%d (0x%x)\n", file, line, pc)
+		f := Frame{PC: pc, File: file, Line: line, Function: string(function(pc))}
 		if file != lastFile {
 			data, err := ioutil.ReadFile(file)
 			if err != nil {
+				frames = append(frames, f)
 				continue
 			}
 			lines = bytes.Split(data, []byte{'\n'})
 			lastFile = file
 		}
-		fmt.Fprintf(buf, "\t%s: %s\n", function(pc), source(lines, line))
+		f.Source = string(source(lines, line))
+		frames = append(frames, f)
 	}
-	return buf.Bytes()
+	return frames
 }
 
 // source returns a space-trimmed slice of the n'th line.